@@ -0,0 +1,122 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// Endpoint describes a sub-pool of the cluster.
+type Endpoint struct {
+	// Address is the server address of the sub-pool.
+	Address string
+
+	// Load is the number of logical connections in use of the sub-pool.
+	Load int
+}
+
+// Balancer picks a sub-pool of the cluster for each Get.
+// Implementations must be safe for concurrent use.
+type Balancer interface {
+	// Pick return the index of the selected endpoint, endpoints isn't empty.
+	Pick(endpoints []Endpoint) int
+}
+
+type roundRobinBalancer struct {
+	index uint32
+}
+
+// NewRoundRobinBalancer return a balancer picks the endpoints in turn.
+func NewRoundRobinBalancer() Balancer {
+	return &roundRobinBalancer{}
+}
+
+func (b *roundRobinBalancer) Pick(endpoints []Endpoint) int {
+	return int((atomic.AddUint32(&b.index, 1) - 1) % uint32(len(endpoints)))
+}
+
+type randomBalancer struct{}
+
+// NewRandomBalancer return a balancer picks the endpoints randomly.
+func NewRandomBalancer() Balancer {
+	return randomBalancer{}
+}
+
+func (randomBalancer) Pick(endpoints []Endpoint) int {
+	return rand.Intn(len(endpoints))
+}
+
+type leastLoadedBalancer struct{}
+
+// NewLeastLoadedBalancer return a balancer picks the endpoint with the
+// least logical connections in use.
+func NewLeastLoadedBalancer() Balancer {
+	return leastLoadedBalancer{}
+}
+
+func (leastLoadedBalancer) Pick(endpoints []Endpoint) int {
+	least := 0
+	for i := 1; i < len(endpoints); i++ {
+		if endpoints[i].Load < endpoints[least].Load {
+			least = i
+		}
+	}
+	return least
+}
+
+type weightedBalancer struct {
+	weights map[string]int
+}
+
+// NewWeightedBalancer return a balancer picks the endpoints randomly in
+// proportion to their weights. The weight of an address which isn't in
+// weights is 1, the address with zero weight is never picked unless all
+// of the weights are zero.
+func NewWeightedBalancer(weights map[string]int) Balancer {
+	w := make(map[string]int, len(weights))
+	for address, weight := range weights {
+		w[address] = weight
+	}
+	return &weightedBalancer{weights: w}
+}
+
+func (b *weightedBalancer) weight(address string) int {
+	if weight, ok := b.weights[address]; ok {
+		if weight < 0 {
+			return 0
+		}
+		return weight
+	}
+	return 1
+}
+
+func (b *weightedBalancer) Pick(endpoints []Endpoint) int {
+	total := 0
+	for _, e := range endpoints {
+		total += b.weight(e.Address)
+	}
+	if total == 0 {
+		return rand.Intn(len(endpoints))
+	}
+	n := rand.Intn(total)
+	for i, e := range endpoints {
+		n -= b.weight(e.Address)
+		if n < 0 {
+			return i
+		}
+	}
+	return len(endpoints) - 1
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrBudgetExhausted is the error resulting if the Budget can't afford the
+// connections of a new pool.
+var ErrBudgetExhausted = errors.New("connection budget is exhausted")
+
+// Budget is a cap of physical connections shared by the pools, which is
+// passed by Options.Budget. The connections in the pools and the one-time
+// connections are counted, the retired ones are not counted while draining.
+type Budget struct {
+	limit int
+
+	mu      sync.Mutex
+	used    int
+	holders map[string]int
+	pools   map[*pool]struct{}
+}
+
+// BudgetStats is the statistics of a Budget.
+type BudgetStats struct {
+	// Limit is the maximum number of physical connections.
+	Limit int
+
+	// Used is the number of physical connections held by the pools.
+	Used int
+
+	// Holders is the number of physical connections held by each address.
+	Holders map[string]int
+}
+
+// NewBudget return a budget of limit physical connections.
+func NewBudget(limit int) *Budget {
+	return &Budget{
+		limit:   limit,
+		holders: make(map[string]int),
+		pools:   make(map[*pool]struct{}),
+	}
+}
+
+// Stats returns the statistics of the budget.
+func (b *Budget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := BudgetStats{
+		Limit:   b.limit,
+		Used:    b.used,
+		Holders: make(map[string]int, len(b.holders)),
+	}
+	for address, n := range b.holders {
+		s.Holders[address] = n
+	}
+	return s
+}
+
+// available return the number of physical connections can be acquired.
+func (b *Budget) available() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit - b.used
+}
+
+// acquire takes at most n physical connections for the address, and
+// return the number taken.
+func (b *Budget) acquire(address string, n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if remain := b.limit - b.used; n > remain {
+		n = remain
+	}
+	if n <= 0 {
+		return 0
+	}
+	b.used += n
+	b.holders[address] += n
+	return n
+}
+
+// release gives n physical connections of the address back, and wakes up
+// the waiters of the pools sharing the budget.
+func (b *Budget) release(address string, n int) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	if b.holders[address] -= n; b.holders[address] <= 0 {
+		delete(b.holders, address)
+	}
+	var waiting []*pool
+	for p := range b.pools {
+		if p.waiters.len() > 0 {
+			waiting = append(waiting, p)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, p := range waiting {
+		p.notify()
+	}
+}
+
+func (b *Budget) register(p *pool) {
+	b.mu.Lock()
+	b.pools[p] = struct{}{}
+	b.mu.Unlock()
+}
+
+func (b *Budget) unregister(p *pool) {
+	b.mu.Lock()
+	delete(b.pools, p)
+	b.mu.Unlock()
+}
+
+// acquireBudget takes at most n physical connections from Options.Budget,
+// it's always n if the pool has no budget.
+func (p *pool) acquireBudget(n int) int {
+	if p.opt.Budget == nil {
+		return n
+	}
+	granted := p.opt.Budget.acquire(p.address, n)
+	if granted < n {
+		atomic.AddUint64(&p.counters.budgetDenials, 1)
+	}
+	return granted
+}
+
+// releaseBudget gives n physical connections back to Options.Budget.
+func (p *pool) releaseBudget(n int) {
+	if p.opt.Budget != nil {
+		p.opt.Budget.release(p.address, n)
+	}
+}
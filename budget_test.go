@@ -0,0 +1,129 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newBudgetOptions(budget *Budget) Options {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 4
+	opt.MaxConcurrentStreams = 1
+	opt.Budget = budget
+	return opt
+}
+
+func TestBudgetNew(t *testing.T) {
+	budget := NewBudget(3)
+	opt := newBudgetOptions(budget)
+	opt.MaxIdle = 2
+
+	p, err := New(addresses[0], opt)
+	require.NoError(t, err)
+	_, err = New(addresses[1], opt)
+	require.Equal(t, ErrBudgetExhausted, err)
+	require.Equal(t, BudgetStats{Limit: 3, Used: 2, Holders: map[string]int{addresses[0]: 2}}, budget.Stats())
+
+	p.Close()
+	require.Equal(t, BudgetStats{Limit: 3, Used: 0, Holders: map[string]int{}}, budget.Stats())
+}
+
+func TestBudgetLazyClose(t *testing.T) {
+	budget := NewBudget(4)
+	opt := newBudgetOptions(budget)
+	opt.MaxIdle = 2
+	opt.InitMode = InitLazy
+
+	// closed before the first Get, the connections are never dialed.
+	p, err := New(addresses[0], opt)
+	require.NoError(t, err)
+	require.Equal(t, 2, budget.Stats().Used)
+	require.NoError(t, p.Close())
+	require.Equal(t, BudgetStats{Limit: 4, Used: 0, Holders: map[string]int{}}, budget.Stats())
+	_, err = p.Get()
+	require.Equal(t, ErrClosed, err)
+	require.Equal(t, 0, budget.Stats().Used)
+}
+
+func TestBudgetReuse(t *testing.T) {
+	budget := NewBudget(2)
+	p, err := New(addresses[0], newBudgetOptions(budget))
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	// the third can't grow the pool, the connections are reused.
+	var conns []Conn
+	for i := 0; i < 3; i++ {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		conns = append(conns, conn)
+		if i > 0 {
+			waitGrow(t, nativePool, 2)
+		}
+	}
+	s := p.Stats()
+	require.Equal(t, 2, s.Current)
+	require.Equal(t, uint64(1), s.BudgetDenials)
+	require.Equal(t, 2, budget.Stats().Used)
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	require.Equal(t, 1, p.Stats().Current)
+	require.Equal(t, 1, budget.Stats().Used)
+}
+
+func TestBudgetWait(t *testing.T) {
+	budget := NewBudget(2)
+	opt := newBudgetOptions(budget)
+	opt.Wait = true
+	p1, err := New(addresses[0], opt)
+	require.NoError(t, err)
+	defer p1.Close()
+	p2, err := New(addresses[1], opt)
+	require.NoError(t, err)
+
+	conn, err := p1.Get()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = p1.GetContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// the budget of the closed pool wakes up the waiter.
+	done := make(chan error, 1)
+	go func() {
+		conn, err := p1.Get()
+		if err == nil {
+			conn.Close()
+		}
+		done <- err
+	}()
+	waitFor(t, func() bool { return p1.Stats().Waiters == 1 })
+	p2.Close()
+	require.NoError(t, <-done)
+	waitGrow(t, p1.(*pool), 2)
+	require.Equal(t, map[string]int{addresses[0]: 2}, budget.Stats().Holders)
+}
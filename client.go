@@ -0,0 +1,54 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Invoke see grpc.ClientConnInterface. It acquires a logical connection
+// from the pool and gives it back when the unary RPC is done.
+func (p *pool) Invoke(ctx context.Context, method string, args, reply interface{},
+	opts ...grpc.CallOption) error {
+	conn, err := p.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Value().Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream see grpc.ClientConnInterface. It acquires a logical connection
+// from the pool and holds it until the stream is finished.
+func (p *pool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, err := p.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the conn may be closed by both OnFinish and the error below,
+	// it only releases the logical connection once.
+	opts = append(opts, grpc.OnFinish(func(error) { conn.Close() }))
+
+	cs, err := conn.Value().NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return cs, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shimingyah/pool/example/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type echoServer struct {
+	pb.UnimplementedEchoServer
+}
+
+func (s *echoServer) Say(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// echoStream echoes every message of a bidirectional stream.
+func echoStream(srv interface{}, stream grpc.ServerStream) error {
+	for {
+		req := new(pb.EchoRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := stream.SendMsg(&pb.EchoResponse{Message: req.Message}); err != nil {
+			return err
+		}
+	}
+}
+
+var streamDesc = grpc.StreamDesc{
+	StreamName:    "Echo",
+	Handler:       echoStream,
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// newServer starts an echo server on a random local port and return its address.
+func newServer(t *testing.T) string {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	pb.RegisterEchoServer(s, &echoServer{})
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Stream",
+		HandlerType: (*interface{})(nil),
+		Streams:     []grpc.StreamDesc{streamDesc},
+	}, struct{}{})
+	go s.Serve(listen)
+	t.Cleanup(s.Stop)
+
+	return listen.Addr().String()
+}
+
+func TestInvoke(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	p, err := New(newServer(t), opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	client := pb.NewEchoClient(p)
+	res, err := client.Say(context.Background(), &pb.EchoRequest{Message: []byte("hi")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hi"), res.Message)
+	require.EqualValues(t, 0, p.(*pool).ref)
+}
+
+func TestNewStream(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	p, err := New(newServer(t), opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	stream, err := p.NewStream(context.Background(), &streamDesc, "/pb.Stream/Echo")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, nativePool.ref)
+
+	for _, msg := range []string{"a", "b"} {
+		require.NoError(t, stream.SendMsg(&pb.EchoRequest{Message: []byte(msg)}))
+		res := new(pb.EchoResponse)
+		require.NoError(t, stream.RecvMsg(res))
+		require.Equal(t, []byte(msg), res.Message)
+		require.EqualValues(t, 1, nativePool.ref)
+	}
+
+	require.NoError(t, stream.CloseSend())
+	require.Equal(t, io.EOF, stream.RecvMsg(new(pb.EchoResponse)))
+	require.EqualValues(t, 0, nativePool.ref)
+}
+
+func TestNewStreamCancel(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	p, err := New(newServer(t), opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = p.NewStream(ctx, &streamDesc, "/pb.Stream/Echo")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, nativePool.ref)
+
+	cancel()
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&nativePool.ref) == 0
+	})
+}
@@ -0,0 +1,369 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// ErrNoAddress is the error resulting if all of the addresses are removed from the cluster.
+var ErrNoAddress = errors.New("no address in cluster")
+
+// Cluster is a pool across a set of backend addresses. It keeps a sub-pool
+// per address and spreads Get() across them by Options.Balancer.
+type Cluster interface {
+	Pool
+
+	// Add creates a sub-pool for the address and adds it to the cluster.
+	Add(address string) error
+
+	// Remove removes the address from the cluster, its sub-pool is shut down
+	// in background after the outstanding Conns are closed or
+	// Options.RemoveTimeout.
+	Remove(address string) error
+
+	// Addresses returns the addresses of the cluster.
+	Addresses() []string
+}
+
+type cluster struct {
+	// pool options of each address
+	opt Options
+
+	// the balancer to pick sub-pool
+	balancer Balancer
+
+	// closed set true when Close is called.
+	closed int32
+
+	// protect the addresses and pools.
+	sync.RWMutex
+	addresses []string
+	pools     []*pool
+}
+
+// NewCluster return a pool across the addresses, each address has
+// a sub-pool created by New with the option.
+func NewCluster(addresses []string, option Options) (Cluster, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("invalid addresses settings")
+	}
+
+	c := &cluster{
+		opt:      option,
+		balancer: option.Balancer,
+	}
+	if c.balancer == nil {
+		c.balancer = NewRoundRobinBalancer()
+	}
+
+	for _, address := range addresses {
+		if err := c.Add(address); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *cluster) index(address string) int {
+	for i, addr := range c.addresses {
+		if addr == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add see Cluster interface.
+func (c *cluster) Add(address string) error {
+	c.RLock()
+	exist := c.index(address) >= 0
+	c.RUnlock()
+	if exist {
+		return fmt.Errorf("address %s already exists", address)
+	}
+
+	c.RLock()
+	opt := c.opt
+	c.RUnlock()
+	p, err := New(address, opt)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if atomic.LoadInt32(&c.closed) == 1 || c.index(address) >= 0 {
+		c.Unlock()
+		p.Close()
+		if atomic.LoadInt32(&c.closed) == 1 {
+			return ErrClosed
+		}
+		return fmt.Errorf("address %s already exists", address)
+	}
+	c.addresses = append(c.addresses, address)
+	c.pools = append(c.pools, p.(*pool))
+	c.Unlock()
+	return nil
+}
+
+// Remove see Cluster interface.
+func (c *cluster) Remove(address string) error {
+	c.Lock()
+	i := c.index(address)
+	if i < 0 {
+		c.Unlock()
+		return fmt.Errorf("address %s doesn't exist", address)
+	}
+	p := c.pools[i]
+	// copy on write, the slices may be read by pick without lock.
+	c.addresses = append(c.addresses[:i:i], c.addresses[i+1:]...)
+	c.pools = append(c.pools[:i:i], c.pools[i+1:]...)
+	timeout := c.opt.RemoveTimeout
+	c.Unlock()
+
+	// no more Conn is returned by the sub-pool, the returned ones are usable.
+	p.stop()
+	go func() {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		p.Shutdown(ctx)
+	}()
+	return nil
+}
+
+// removed return whether the sub-pool is removed from the cluster.
+func (c *cluster) removed(p *pool) bool {
+	c.RLock()
+	defer c.RUnlock()
+	for _, sub := range c.pools {
+		if sub == p {
+			return false
+		}
+	}
+	return true
+}
+
+// do calls fn with the picked sub-pool, it picks again if the sub-pool is
+// closed by Remove meanwhile.
+func (c *cluster) do(fn func(p *pool) error) error {
+	for {
+		p, err := c.pick()
+		if err != nil {
+			return err
+		}
+		if err = fn(p); !errors.Is(err, ErrClosed) || !c.removed(p) {
+			return err
+		}
+	}
+}
+
+// Addresses see Cluster interface.
+func (c *cluster) Addresses() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return append([]string(nil), c.addresses...)
+}
+
+// pick selects a sub-pool by the balancer.
+func (c *cluster) pick() (*pool, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, ErrClosed
+	}
+	c.RLock()
+	addresses, pools := c.addresses, c.pools
+	c.RUnlock()
+	if len(pools) == 0 {
+		return nil, ErrNoAddress
+	}
+	if len(pools) == 1 {
+		return pools[0], nil
+	}
+
+	endpoints := make([]Endpoint, len(pools))
+	for i, p := range pools {
+		endpoints[i] = Endpoint{
+			Address: addresses[i],
+			Load:    int(atomic.LoadInt32(&p.ref)),
+		}
+	}
+	return pools[c.balancer.Pick(endpoints)], nil
+}
+
+// Get see Pool interface.
+func (c *cluster) Get() (Conn, error) {
+	return c.GetContext(context.Background())
+}
+
+// GetContext see Pool interface.
+func (c *cluster) GetContext(ctx context.Context) (Conn, error) {
+	var conn Conn
+	err := c.do(func(p *pool) (err error) {
+		conn, err = p.GetContext(ctx)
+		return err
+	})
+	return conn, err
+}
+
+// Invoke see grpc.ClientConnInterface.
+func (c *cluster) Invoke(ctx context.Context, method string, args, reply interface{},
+	opts ...grpc.CallOption) error {
+	return c.do(func(p *pool) error {
+		return p.Invoke(ctx, method, args, reply, opts...)
+	})
+}
+
+// NewStream see grpc.ClientConnInterface.
+func (c *cluster) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	var cs grpc.ClientStream
+	err := c.do(func(p *pool) (err error) {
+		cs, err = p.NewStream(ctx, desc, method, opts...)
+		return err
+	})
+	return cs, err
+}
+
+// Close see Pool interface.
+func (c *cluster) Close() error {
+	for _, p := range c.stop() {
+		p.Close()
+	}
+	return nil
+}
+
+// Resize see Pool interface, the sub-pools and the ones added later are resized.
+func (c *cluster) Resize(maxIdle, maxActive, maxConcurrentStreams int) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrClosed
+	}
+	c.Lock()
+	if err := checkLimits(c.opt, maxIdle, maxActive, maxConcurrentStreams); err != nil {
+		c.Unlock()
+		return err
+	}
+	pools := c.pools
+	c.opt.MaxIdle = maxIdle
+	c.opt.MaxActive = maxActive
+	c.opt.MaxConcurrentStreams = maxConcurrentStreams
+	c.Unlock()
+
+	for _, p := range pools {
+		if err := p.Resize(maxIdle, maxActive, maxConcurrentStreams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitReady see Pool interface, it waits for all of the sub-pools.
+func (c *cluster) WaitReady(ctx context.Context) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrClosed
+	}
+	c.RLock()
+	pools := c.pools
+	c.RUnlock()
+
+	for _, p := range pools {
+		if err := p.WaitReady(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown see Pool interface. The sub-pools are shut down concurrently.
+func (c *cluster) Shutdown(ctx context.Context) error {
+	pools := c.stop()
+	errs := make(chan error, len(pools))
+	for _, p := range pools {
+		go func(p *pool) {
+			errs <- p.Shutdown(ctx)
+		}(p)
+	}
+
+	var err error
+	for range pools {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// stop marks the cluster closed and return the sub-pools.
+func (c *cluster) stop() []*pool {
+	c.Lock()
+	atomic.StoreInt32(&c.closed, 1)
+	pools := c.pools
+	c.addresses, c.pools = nil, nil
+	c.Unlock()
+	return pools
+}
+
+// Stats see Pool interface.
+func (c *cluster) Stats() PoolStats {
+	c.RLock()
+	pools := c.pools
+	c.RUnlock()
+
+	var s PoolStats
+	for _, p := range pools {
+		s.add(p.Stats())
+	}
+	return s
+}
+
+// Checkouts see Pool interface.
+func (c *cluster) Checkouts() []Checkout {
+	c.RLock()
+	pools := c.pools
+	c.RUnlock()
+
+	var checkouts []Checkout
+	for _, p := range pools {
+		checkouts = append(checkouts, p.Checkouts()...)
+	}
+	sort.Slice(checkouts, func(i, j int) bool {
+		return checkouts[i].Acquired.Before(checkouts[j].Acquired)
+	})
+	return checkouts
+}
+
+// Status see Pool interface.
+func (c *cluster) Status() string {
+	c.RLock()
+	pools := c.pools
+	c.RUnlock()
+
+	status := make([]string, len(pools))
+	for i, p := range pools {
+		status[i] = p.Status()
+	}
+	return strings.Join(status, "\n")
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+var addresses = []string{"127.0.0.1:50001", "127.0.0.1:50002", "127.0.0.1:50003"}
+
+func newCluster(t *testing.T, balancer Balancer) Cluster {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.Balancer = balancer
+	c, err := NewCluster(addresses, opt)
+	require.NoError(t, err)
+	return c
+}
+
+func address(c Conn) string {
+	return c.(*handle).c.pool.address
+}
+
+func TestNewCluster(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+
+	_, err := NewCluster(nil, opt)
+	require.Error(t, err)
+
+	_, err = NewCluster([]string{"127.0.0.1:50001", "127.0.0.1:50001"}, opt)
+	require.Error(t, err)
+
+	c := newCluster(t, nil)
+	require.Equal(t, addresses, c.Addresses())
+	c.Close()
+
+	_, err = c.Get()
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestClusterRoundRobin(t *testing.T) {
+	c := newCluster(t, nil)
+	defer c.Close()
+
+	for i := 0; i < 6; i++ {
+		conn, err := c.Get()
+		require.NoError(t, err)
+		require.Equal(t, addresses[i%len(addresses)], address(conn))
+		conn.Close()
+	}
+}
+
+func TestClusterAddRemove(t *testing.T) {
+	c := newCluster(t, nil)
+	defer c.Close()
+
+	require.Error(t, c.Add(addresses[0]))
+	require.NoError(t, c.Add("127.0.0.1:50004"))
+	require.Equal(t, append(addresses, "127.0.0.1:50004"), c.Addresses())
+
+	require.Error(t, c.Remove("127.0.0.1:50005"))
+	for _, addr := range c.Addresses() {
+		require.NoError(t, c.Remove(addr))
+	}
+	require.Empty(t, c.Addresses())
+
+	_, err := c.Get()
+	require.Equal(t, ErrNoAddress, err)
+}
+
+func TestClusterRemoveDrain(t *testing.T) {
+	c := newCluster(t, nil)
+	defer c.Close()
+
+	conn, err := c.Get()
+	require.NoError(t, err)
+	removed := conn.(*handle).c.pool
+	require.NoError(t, c.Remove(removed.address))
+
+	// the sub-pool is closed after the outstanding conn is closed.
+	time.Sleep(100 * time.Millisecond)
+	require.NotEqual(t, connectivity.Shutdown, conn.Value().GetState())
+	require.NoError(t, conn.Close())
+	waitFor(t, func() bool {
+		return conn.Value().GetState() == connectivity.Shutdown
+	})
+}
+
+// removeBalancer removes the picked address from the cluster on the first pick.
+type removeBalancer struct {
+	c       Cluster
+	removed string
+}
+
+func (b *removeBalancer) Pick(endpoints []Endpoint) int {
+	if b.removed == "" {
+		b.removed = endpoints[0].Address
+		b.c.Remove(b.removed)
+	}
+	return 0
+}
+
+func TestClusterPickRemoved(t *testing.T) {
+	b := &removeBalancer{}
+	c := newCluster(t, b)
+	defer c.Close()
+	b.c = c
+
+	conn, err := c.Get()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, addresses[0], b.removed)
+	require.Equal(t, addresses[1], address(conn))
+}
+
+func TestClusterLeastLoaded(t *testing.T) {
+	c := newCluster(t, NewLeastLoadedBalancer())
+	defer c.Close()
+
+	conns := make([]Conn, 0, len(addresses))
+	for i := range addresses {
+		conn, err := c.Get()
+		require.NoError(t, err)
+		require.Equal(t, addresses[i], address(conn))
+		conns = append(conns, conn)
+	}
+
+	conns[1].Close()
+	conn, err := c.Get()
+	require.NoError(t, err)
+	require.Equal(t, addresses[1], address(conn))
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	b := NewWeightedBalancer(map[string]int{addresses[0]: 0, addresses[1]: 3})
+	endpoints := []Endpoint{{Address: addresses[0]}, {Address: addresses[1]}, {Address: addresses[2]}}
+
+	count := make([]int, len(endpoints))
+	for i := 0; i < 1000; i++ {
+		count[b.Pick(endpoints)]++
+	}
+	require.Equal(t, 0, count[0])
+	require.True(t, count[1] > count[2])
+}
@@ -15,40 +15,123 @@
 package pool
 
 import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
 	"google.golang.org/grpc"
 )
 
+// ErrConnClosed is the error resulting if the Conn is closed more than once.
+var ErrConnClosed = errors.New("conn is already closed")
+
 // Conn single grpc connection inerface
 type Conn interface {
 	// Value return the actual grpc connection type *grpc.ClientConn.
 	Value() *grpc.ClientConn
 
 	// Close decrease the reference of grpc connection, instead of close it.
-	// if the pool is full, just close it.
+	// if the pool is full, just close it. Each Conn returned by Get releases
+	// the reference exactly once, the later calls return ErrConnClosed.
 	Close() error
 }
 
-// Conn is wrapped grpc.ClientConn. to provide close and value method.
+// handle is a checkout of the physical connection returned by Get.
+type handle struct {
+	c *conn
+
+	// atomic, set to 1 when Close is called.
+	closed int32
+
+	// the time and stack of Get, recorded only if leak detection is enabled.
+	acquired time.Time
+	stack    string
+
+	// reported is set when the handle is reported as leak, protected by tracker.
+	reported bool
+}
+
+// Value see Conn interface.
+func (h *handle) Value() *grpc.ClientConn {
+	return h.c.cc
+}
+
+// Close see Conn interface.
+func (h *handle) Close() error {
+	if !atomic.CompareAndSwapInt32(&h.closed, 0, 1) {
+		return ErrConnClosed
+	}
+	if !h.acquired.IsZero() {
+		h.c.pool.tracker.remove(h)
+	}
+	return h.c.release()
+}
+
+// conn is wrapped grpc.ClientConn, it's the physical connection of pool.
 type conn struct {
+	// atomic, the number of times the connection returned by Get.
+	uses uint64
+
+	// atomic, the unix nano time the connection is returned by Get or closed.
+	lastUsed int64
+
 	cc   *grpc.ClientConn
 	pool *pool
 	once bool
+
+	// atomic, set to 1 by health checking if the connection is unhealthy.
+	unhealthy int32
+
+	// atomic, the number of consecutive redials of the slot by health
+	// checking, and the unix nano time before which it's not redialed again.
+	redials  int32
+	redialAt int64
+
+	// atomic, the number of logical connections in use of the connection.
+	inflight int32
+
+	// atomic, 1 if the connection is retired from the pool, 2 if the
+	// retired grpc connection is closed.
+	retired int32
+
+	// the time the connection is created.
+	created time.Time
+
+	// the time the connection should be retired, zero means never.
+	expire time.Time
 }
 
-// Value see Conn interface.
-func (c *conn) Value() *grpc.ClientConn {
-	return c.cc
+// checkout return a new handle of the connection.
+func (c *conn) checkout() Conn {
+	h := &handle{c: c}
+	if c.pool.opt.LeakThreshold > 0 {
+		c.pool.track(h)
+	}
+	return h
 }
 
-// Close see Conn interface.
-func (c *conn) Close() error {
+// release gives a logical connection back to the pool.
+func (c *conn) release() error {
+	// read once before decrRef, the conn may be reset by the pool after that.
+	once := c.once
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+	if atomic.AddInt32(&c.inflight, -1) == 0 && atomic.LoadInt32(&c.retired) == 1 {
+		c.closeRetired()
+	}
 	c.pool.decrRef()
-	if c.once {
+	if once {
+		atomic.AddUint64(&c.pool.counters.oneTimeClosed, 1)
+		c.pool.releaseBudget(1)
 		return c.reset()
 	}
 	return nil
 }
 
+func (c *conn) healthy() bool {
+	return atomic.LoadInt32(&c.unhealthy) == 0
+}
+
 func (c *conn) reset() error {
 	cc := c.cc
 	c.cc = nil
@@ -59,10 +142,36 @@ func (c *conn) reset() error {
 	return nil
 }
 
+// retire marks the connection retired from the pool, the grpc connection
+// is closed after all of the logical connections in use are closed.
+func (c *conn) retire() {
+	atomic.StoreInt32(&c.retired, 1)
+	if atomic.LoadInt32(&c.inflight) == 0 {
+		c.closeRetired()
+	}
+}
+
+func (c *conn) closeRetired() {
+	if atomic.CompareAndSwapInt32(&c.retired, 1, 2) {
+		c.cc.Close()
+	}
+}
+
 func (p *pool) wrapConn(cc *grpc.ClientConn, once bool) *conn {
-	return &conn{
-		cc:   cc,
-		pool: p,
-		once: once,
+	now := time.Now()
+	c := &conn{
+		cc:       cc,
+		pool:     p,
+		once:     once,
+		lastUsed: now.UnixNano(),
+		created:  now,
+	}
+	if p.opt.MaxConnLifetime > 0 && !once {
+		lifetime := p.opt.MaxConnLifetime
+		if p.opt.MaxConnLifetimeJitter > 0 {
+			lifetime += time.Duration(rand.Int63n(int64(p.opt.MaxConnLifetimeJitter)))
+		}
+		c.expire = now.Add(lifetime)
 	}
+	return c
 }
@@ -0,0 +1,315 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventLogSize is the number of recent events kept by each pool.
+const eventLogSize = 32
+
+// Event is a recent change of the pool shown by DebugHandler.
+type Event struct {
+	// Time is when the event happened.
+	Time time.Time
+
+	// Kind is one of grow, shrink and evict.
+	Kind string
+
+	// From and To are the number of physical connections of grow and shrink.
+	From int
+	To   int
+
+	// Reason is the EvictReason of evict.
+	Reason string
+}
+
+// eventLog is a ring of the recent events.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+}
+
+func (l *eventLog) add(e Event) {
+	e.Time = time.Now()
+	l.mu.Lock()
+	if len(l.events) < eventLogSize {
+		l.events = append(l.events, e)
+	} else {
+		l.events[l.next] = e
+	}
+	l.next = (l.next + 1) % eventLogSize
+	l.mu.Unlock()
+}
+
+// recent return the events in the order they happened.
+func (l *eventLog) recent() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) < eventLogSize {
+		return append([]Event(nil), l.events...)
+	}
+	return append(append([]Event(nil), l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// DebugOptions is the printable part of Options.
+type DebugOptions struct {
+	MaxIdle               int
+	MinReady              int
+	MaxActive             int
+	MaxConcurrentStreams  int
+	InitMode              string
+	Reuse                 bool
+	Wait                  bool
+	HealthCheckInterval   time.Duration
+	HealthCheckRPC        bool
+	HealthCheckService    string
+	IdleTimeout           time.Duration
+	MaxConnLifetime       time.Duration
+	MaxConnLifetimeJitter time.Duration
+	LeakThreshold         time.Duration
+	ShrinkInterval        time.Duration
+
+	// the types of the pluggable options, empty if not set.
+	Selector      string
+	ScalingPolicy string
+	Budget        *BudgetStats `json:",omitempty"`
+}
+
+// PoolDebug is the internals of a pool shown by DebugHandler.
+type PoolDebug struct {
+	// Name is the name the pool added to DebugHandler with.
+	Name string
+
+	// Address is the server address of the pool.
+	Address string
+
+	// Options is empty for the pools not created by New or NewCluster.
+	Options DebugOptions
+
+	// Stats includes the breakdown of each physical connection.
+	Stats PoolStats
+
+	// Events are the recent grow, shrink and evict events.
+	Events []Event
+
+	// Checkouts are the outstanding Conns recorded by leak detection, and
+	// Leaks are the ones older than Options.LeakThreshold.
+	Checkouts []Checkout
+	Leaks     []Checkout
+}
+
+// debugger is implemented by the pools created by New and NewCluster.
+type debugger interface {
+	debug(name string) []PoolDebug
+}
+
+// options return the options with the current limits.
+func (p *pool) options() Options {
+	opt := p.opt
+	opt.MaxIdle = int(p.maxIdle())
+	opt.MaxActive = int(p.maxActive())
+	opt.MaxConcurrentStreams = int(p.maxStreams())
+	return opt
+}
+
+func (p *pool) debug(name string) []PoolDebug {
+	opt := p.options()
+	d := PoolDebug{
+		Name:    name,
+		Address: p.address,
+		Options: DebugOptions{
+			MaxIdle:               opt.MaxIdle,
+			MinReady:              int(p.minReady()),
+			MaxActive:             opt.MaxActive,
+			MaxConcurrentStreams:  opt.MaxConcurrentStreams,
+			InitMode:              opt.InitMode.String(),
+			Reuse:                 opt.Reuse,
+			Wait:                  opt.Wait,
+			HealthCheckInterval:   opt.HealthCheckInterval,
+			HealthCheckRPC:        opt.HealthCheckRPC,
+			HealthCheckService:    opt.HealthCheckService,
+			IdleTimeout:           opt.IdleTimeout,
+			MaxConnLifetime:       opt.MaxConnLifetime,
+			MaxConnLifetimeJitter: opt.MaxConnLifetimeJitter,
+			LeakThreshold:         opt.LeakThreshold,
+			ShrinkInterval:        opt.ShrinkInterval,
+		},
+		Stats:     p.Stats(),
+		Events:    p.events.recent(),
+		Checkouts: p.Checkouts(),
+	}
+	if opt.Selector != nil {
+		d.Options.Selector = fmt.Sprintf("%T", opt.Selector)
+	}
+	if opt.ScalingPolicy != nil {
+		d.Options.ScalingPolicy = fmt.Sprintf("%T", opt.ScalingPolicy)
+	}
+	if opt.Budget != nil {
+		s := opt.Budget.Stats()
+		d.Options.Budget = &s
+	}
+	for _, c := range d.Checkouts {
+		if opt.LeakThreshold > 0 && c.Age >= opt.LeakThreshold {
+			d.Leaks = append(d.Leaks, c)
+		}
+	}
+	return []PoolDebug{d}
+}
+
+func (c *cluster) debug(name string) []PoolDebug {
+	c.RLock()
+	pools := c.pools
+	c.RUnlock()
+
+	var ds []PoolDebug
+	for _, p := range pools {
+		ds = append(ds, p.debug(name)...)
+	}
+	return ds
+}
+
+// DebugHandler is an http.Handler rendering the internals of the pools,
+// such as the connections, recent events and outstanding checkouts. It
+// serves HTML, or JSON if the format query is json or the Accept header
+// is application/json. It's usually mounted at /debug/pool.
+type DebugHandler struct {
+	mu    sync.RWMutex
+	pools map[string]Pool
+}
+
+// NewDebugHandler return an empty debug handler.
+func NewDebugHandler() *DebugHandler {
+	return &DebugHandler{pools: make(map[string]Pool)}
+}
+
+// Add adds the pool with the name to the handler.
+func (h *DebugHandler) Add(name string, p Pool) {
+	h.mu.Lock()
+	h.pools[name] = p
+	h.mu.Unlock()
+}
+
+// Remove removes the pool with the name from the handler.
+func (h *DebugHandler) Remove(name string) {
+	h.mu.Lock()
+	delete(h.pools, name)
+	h.mu.Unlock()
+}
+
+// Pools returns the internals of the pools ordered by name, the pool
+// created by NewCluster has one for each sub-pool.
+func (h *DebugHandler) Pools() []PoolDebug {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.pools))
+	pools := make(map[string]Pool, len(h.pools))
+	for name, p := range h.pools {
+		names = append(names, name)
+		pools[name] = p
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	var ds []PoolDebug
+	for _, name := range names {
+		p := pools[name]
+		if d, ok := p.(debugger); ok {
+			ds = append(ds, d.debug(name)...)
+			continue
+		}
+		s := p.Stats()
+		ds = append(ds, PoolDebug{Name: name, Address: s.Address, Stats: s, Checkouts: p.Checkouts()})
+	}
+	return ds
+}
+
+// ServeHTTP implements http.Handler.
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ds := h.Pools()
+	if r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(ds)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugTemplate.Execute(w, ds); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var debugTemplate = template.Must(template.New("pool").Funcs(template.FuncMap{
+	"since": func(t time.Time) time.Duration {
+		return time.Since(t).Round(time.Millisecond)
+	},
+	"round": func(d time.Duration) time.Duration {
+		return d.Round(time.Millisecond)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>/debug/pool</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.Name}} {{.Address}}</h2>
+<table>
+<tr><th>MaxIdle</th><th>MinReady</th><th>MaxActive</th><th>MaxConcurrentStreams</th><th>InitMode</th><th>Reuse</th><th>Wait</th>
+<th>HealthCheckInterval</th><th>IdleTimeout</th><th>MaxConnLifetime</th><th>LeakThreshold</th><th>Selector</th><th>ScalingPolicy</th></tr>
+{{with .Options}}<tr><td>{{.MaxIdle}}</td><td>{{.MinReady}}</td><td>{{.MaxActive}}</td><td>{{.MaxConcurrentStreams}}</td><td>{{.InitMode}}</td><td>{{.Reuse}}</td><td>{{.Wait}}</td>
+<td>{{.HealthCheckInterval}}</td><td>{{.IdleTimeout}}</td><td>{{.MaxConnLifetime}}</td><td>{{.LeakThreshold}}</td><td>{{.Selector}}</td><td>{{.ScalingPolicy}}</td></tr>{{end}}
+</table>
+{{with .Stats}}
+<table>
+<tr><th>Current</th><th>Active</th><th>Idle</th><th>Ref</th><th>Waiters</th><th>Gets</th><th>Grows</th><th>Shrinks</th><th>DialFailures</th><th>LastDialError</th><th>OneTime</th></tr>
+<tr><td>{{.Current}}</td><td>{{.Active}}</td><td>{{.Idle}}</td><td>{{.Ref}}</td><td>{{.Waiters}}</td><td>{{.Gets}}</td><td>{{.Grows}}</td><td>{{.Shrinks}}</td><td>{{.DialFailures}}</td><td>{{.LastDialError}}</td><td>{{.OneTimeClosed}}/{{.OneTimeCreated}}</td></tr>
+</table>
+<h3>Connections</h3>
+<table>
+<tr><th>Slot</th><th>State</th><th>Healthy</th><th>InFlight</th><th>Age</th><th>Uses</th></tr>
+{{range .Conns}}<tr><td>{{.Index}}</td><td>{{.State}}</td><td>{{.Healthy}}</td><td>{{.InFlight}}</td><td>{{round .Age}}</td><td>{{.Uses}}</td></tr>
+{{end}}</table>
+{{end}}
+<h3>Events</h3>
+<table>
+<tr><th>Ago</th><th>Kind</th><th>From</th><th>To</th><th>Reason</th></tr>
+{{range .Events}}<tr><td>{{since .Time}}</td><td>{{.Kind}}</td><td>{{.From}}</td><td>{{.To}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+<h3>Checkouts ({{len .Checkouts}}, {{len .Leaks}} leak suspects)</h3>
+<table>
+<tr><th>Age</th><th>Stack</th></tr>
+{{range .Checkouts}}<tr><td>{{round .Age}}</td><td><pre>{{.Stack}}</pre></td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,96 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLog(t *testing.T) {
+	var l eventLog
+	for i := 0; i < eventLogSize+2; i++ {
+		l.add(Event{Kind: "grow", To: i})
+	}
+	events := l.recent()
+	require.Len(t, events, eventLogSize)
+	require.Equal(t, 2, events[0].To)
+	require.Equal(t, eventLogSize+1, events[eventLogSize-1].To)
+}
+
+func TestDebugHandler(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.LeakThreshold = time.Hour
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	defer conn1.Close()
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	defer conn2.Close()
+	waitGrow(t, nativePool, 2)
+
+	c := newCluster(t, nil)
+	defer c.Close()
+
+	h := NewDebugHandler()
+	h.Add("echo", p)
+	h.Add("cluster", c)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pool?format=json", nil))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var ds []PoolDebug
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ds))
+	require.Len(t, ds, 1+len(addresses))
+	for i, address := range addresses {
+		require.Equal(t, "cluster", ds[i].Name)
+		require.Equal(t, address, ds[i].Address)
+	}
+
+	d := ds[len(addresses)]
+	require.Equal(t, "echo", d.Name)
+	require.Equal(t, *endpoint, d.Address)
+	require.Equal(t, 2, d.Options.MaxActive)
+	require.Equal(t, "sync", d.Options.InitMode)
+	require.Len(t, d.Stats.Conns, 2)
+	require.Equal(t, 2, d.Stats.Conns[0].InFlight+d.Stats.Conns[1].InFlight)
+	require.Len(t, d.Events, 2)
+	require.Equal(t, "grow", d.Events[1].Kind)
+	require.Equal(t, []int{1, 2}, []int{d.Events[1].From, d.Events[1].To})
+	require.Len(t, d.Checkouts, 2)
+	require.Empty(t, d.Leaks)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pool", nil))
+	require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	require.Contains(t, w.Body.String(), "<h2>echo "+*endpoint+"</h2>")
+	require.Contains(t, w.Body.String(), "Checkouts (2, 0 leak suspects)")
+
+	h.Remove("cluster")
+	require.Len(t, h.Pools(), 1)
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// DialConfig is the profile of the built-in dialer, the zero fields take
+// the grpc defaults.
+type DialConfig struct {
+	// Timeout bounds the dial blocked by Block, zero means no timeout.
+	Timeout time.Duration
+
+	// Block makes the dial wait until the connection is ready, so an
+	// unreachable address fails the dial and the pool backs off. By default
+	// the dial returns immediately and connects in background.
+	Block bool
+
+	// BackoffMaxDelay is the maximum delay when backing off after failed
+	// connection attempts.
+	BackoffMaxDelay time.Duration
+
+	// KeepAlive is the keepalive params of the client, it's disabled if
+	// KeepAlive.Time is zero.
+	KeepAlive keepalive.ClientParameters
+
+	// InitialWindowSize and InitialConnWindowSize are the flow control
+	// windows of each stream and of each connection.
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+
+	// MaxSendMsgSize and MaxRecvMsgSize limit the message size of each RPC.
+	MaxSendMsgSize int
+	MaxRecvMsgSize int
+
+	// Credentials secures the connections. When nil, TLSFiles or TLS is
+	// used if it's not nil, or the connections are insecure.
+	Credentials credentials.TransportCredentials
+	TLS         *tls.Config
+
+	// TLSFiles are reloaded by the pool when changed, and the connections
+	// are rotated. The standalone Dial loads them once.
+	TLSFiles *TLSFiles
+
+	// PerRPCCredentials attaches the security information to each RPC.
+	PerRPCCredentials credentials.PerRPCCredentials
+
+	// UserAgent is prepended to the grpc user agent.
+	UserAgent string
+
+	// Authority is the :authority pseudo-header and the TLS server name,
+	// empty means the dialed address.
+	Authority string
+
+	// Compressor is the name of the registered compressor used by each RPC,
+	// such as "gzip" registered by google.golang.org/grpc/encoding/gzip.
+	Compressor string
+
+	// DialOptions are appended after the options built from the fields.
+	DialOptions []grpc.DialOption
+}
+
+// DefaultDialConfig is the profile of Dial.
+// Feel free to copy and modify it to suit your needs.
+var DefaultDialConfig = DialConfig{
+	Timeout:         DialTimeout,
+	BackoffMaxDelay: BackoffMaxDelay,
+	KeepAlive: keepalive.ClientParameters{
+		Time:                KeepAliveTime,
+		Timeout:             KeepAliveTimeout,
+		PermitWithoutStream: true,
+	},
+	InitialWindowSize:     InitialWindowSize,
+	InitialConnWindowSize: InitialConnWindowSize,
+	MaxSendMsgSize:        MaxSendMsgSize,
+	MaxRecvMsgSize:        MaxRecvMsgSize,
+}
+
+// Dial return a grpc connection configured by the profile.
+func (c DialConfig) Dial(address string) (*grpc.ClientConn, error) {
+	if c.Credentials == nil && c.TLSFiles != nil {
+		creds, err := c.TLSFiles.load()
+		if err != nil {
+			return nil, err
+		}
+		c.Credentials = creds
+	}
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cc, err := grpc.DialContext(ctx, address, c.dialOptions()...)
+	if err != nil || !c.Block {
+		return cc, err
+	}
+	if err := waitReady(ctx, cc); err != nil {
+		cc.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+// waitReady blocks until the connection is ready or the ctx is done.
+func waitReady(ctx context.Context, cc *grpc.ClientConn) error {
+	cc.Connect()
+	for {
+		state := cc.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !cc.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
+
+// dialOptions return the grpc dial options of the non-zero fields.
+func (c DialConfig) dialOptions() []grpc.DialOption {
+	creds := c.Credentials
+	if creds == nil && c.TLS != nil {
+		creds = credentials.NewTLS(c.TLS)
+	}
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+
+	if c.BackoffMaxDelay > 0 {
+		opts = append(opts, grpc.WithBackoffMaxDelay(c.BackoffMaxDelay))
+	}
+	if c.KeepAlive.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(c.KeepAlive))
+	}
+	if c.InitialWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(c.InitialWindowSize))
+	}
+	if c.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(c.InitialConnWindowSize))
+	}
+
+	var callOpts []grpc.CallOption
+	if c.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if c.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.Compressor != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(c.Compressor))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
+	if c.PerRPCCredentials != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(c.PerRPCCredentials))
+	}
+	if c.UserAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(c.UserAgent))
+	}
+	if c.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(c.Authority))
+	}
+	return append(opts, c.DialOptions...)
+}
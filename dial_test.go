@@ -0,0 +1,105 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDialConfig(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	headers := make(chan metadata.MD, 1)
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		headers <- md
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(listen)
+	defer s.Stop()
+
+	config := DefaultDialConfig
+	config.UserAgent = "pool-test"
+	config.Authority = "echo.test"
+	opt := DefaultOptions
+	opt.Dial = nil
+	opt.MaxIdle = 1
+	opt.DialConfig = &config
+	p, err := New(listen.Addr().String(), opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the profile is copied by New.
+	config.UserAgent = "changed"
+
+	_, err = healthpb.NewHealthClient(p).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	md := <-headers
+	require.True(t, strings.HasPrefix(md.Get("user-agent")[0], "pool-test "))
+	require.Equal(t, []string{"echo.test"}, md.Get(":authority"))
+}
+
+func TestDialConfigOptions(t *testing.T) {
+	require.Len(t, DialConfig{}.dialOptions(), 1)
+	// credentials, backoff, keepalive, 2 windows, call options.
+	require.Len(t, DefaultDialConfig.dialOptions(), 6)
+
+	config := DefaultDialConfig
+	config.Compressor = "gzip"
+	config.UserAgent = "pool-test"
+	config.DialOptions = []grpc.DialOption{grpc.WithDisableRetry()}
+	require.Len(t, config.dialOptions(), 8)
+}
+
+func TestDialConfigBlock(t *testing.T) {
+	// nothing listens on the address.
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listen.Addr().String()
+	listen.Close()
+
+	config := DialConfig{Timeout: 100 * time.Millisecond, Block: true}
+	start := time.Now()
+	_, err = config.Dial(address)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.True(t, time.Since(start) >= config.Timeout)
+
+	// the pool fails to fill instead of holding the unreachable connections.
+	opt := DefaultOptions
+	opt.Dial = nil
+	opt.MaxIdle = 1
+	opt.DialConfig = &config
+	_, err = New(address, opt)
+	require.Error(t, err)
+
+	// the default dial doesn't block.
+	start = time.Now()
+	cc, err := DefaultDialConfig.Dial(address)
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < DefaultDialConfig.Timeout)
+	cc.Close()
+}
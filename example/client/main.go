@@ -51,4 +51,11 @@ func main() {
 		log.Fatalf("unexpected error from Say: %v", err)
 	}
 	fmt.Println("rpc response:", res)
+
+	// the pool can also be used as the connection of grpc client directly.
+	res, err = pb.NewEchoClient(p).Say(ctx, &pb.EchoRequest{Message: []byte("hello")})
+	if err != nil {
+		log.Fatalf("unexpected error from Say: %v", err)
+	}
+	fmt.Println("rpc response:", res)
 }
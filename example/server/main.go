@@ -31,7 +31,9 @@ import (
 var port = flag.Int("port", 50000, "port number")
 
 // server implements EchoServer.
-type server struct{}
+type server struct {
+	pb.UnimplementedEchoServer
+}
 
 func (s *server) Say(context.Context, *pb.EchoRequest) (*pb.EchoResponse, error) {
 	return &pb.EchoResponse{Message: []byte("hello world")}, nil
@@ -0,0 +1,210 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ErrNotReady is the error resulting if the pool has no connection dialed
+// yet, it wraps the error of the last failed dial.
+var ErrNotReady = errors.New("pool is not ready")
+
+// signal wakes up all of the waiters when the pool is changed.
+type signal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// wait return a channel closed by the next broadcast.
+func (s *signal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+func (s *signal) broadcast() {
+	s.mu.Lock()
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+	s.mu.Unlock()
+}
+
+// minReady return the number of connections enough to be ready.
+func (p *pool) minReady() int32 {
+	if p.opt.MinReady > 0 {
+		return int32(p.opt.MinReady)
+	}
+	return p.maxIdle()
+}
+
+// initSync dials MaxIdle connections, it fails if less than MinReady of them
+// are dialed, and the missing ones are filled in background.
+func (p *pool) initSync(ctx context.Context) error {
+	type result struct {
+		dialed []*grpc.ClientConn
+		err    error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		dialed, err := p.dial(p.opt.MaxIdle)
+		ch <- result{dialed, err}
+	}()
+
+	var r result
+	select {
+	case r = <-ch:
+	case <-ctx.Done():
+		// close the connections dialed after giving up.
+		go func() {
+			for _, cc := range (<-ch).dialed {
+				cc.Close()
+			}
+		}()
+		p.releaseBudget(p.opt.MaxIdle)
+		return ctx.Err()
+	}
+
+	_, to := p.publish(r.dialed)
+	if to < p.minReady() {
+		// the published ones are released by Close.
+		p.releaseBudget(p.opt.MaxIdle - int(to))
+		return fmt.Errorf("dial is not able to fill the pool: %s", r.err)
+	}
+	if to < p.maxIdle() {
+		p.backoff.fail()
+		p.startFill(p.opt.MaxIdle - int(to))
+	}
+	return nil
+}
+
+// startFill starts filling the pool in background once, reserved is the
+// budget acquired for the missing connections.
+func (p *pool) startFill(reserved int) {
+	p.fillOnce.Do(func() {
+		go p.fill(reserved)
+	})
+}
+
+// fill dials the missing idle connections until the pool has MaxIdle
+// connections or is closed, the failed dials are retried with backoff.
+func (p *pool) fill(reserved int) {
+	defer func() {
+		p.releaseBudget(reserved)
+	}()
+	for atomic.LoadInt32(&p.closed) == 0 {
+		missing := int(p.maxIdle() - atomic.LoadInt32(&p.current))
+		if missing > reserved {
+			missing = reserved
+		}
+		if missing <= 0 {
+			return
+		}
+
+		dialed, err := p.dial(missing)
+		reserved -= len(dialed)
+		from, to := p.publish(dialed)
+		p.releaseBudget(len(dialed) - int(to-from))
+		if to > from {
+			p.opt.Logger.Info("fill pool", "from", from, "to", to, "maxIdle", p.maxIdle())
+		}
+		if err == nil {
+			p.backoff.reset()
+			continue
+		}
+
+		delay := p.backoff.fail()
+		p.opt.Logger.Warn("fill pool dial failed", "address", p.address, "error", err, "backoff", delay)
+		// wake up the Get waiting for the first connection.
+		p.filled.broadcast()
+		select {
+		case <-p.done:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// waitConn waits for the first connection of the empty pool, it return
+// ErrNotReady if the dial is failed and backing off.
+func (p *pool) waitConn(ctx context.Context) error {
+	if p.opt.InitMode == InitLazy {
+		p.startFill(p.opt.MaxIdle)
+	}
+	for {
+		ch := p.filled.wait()
+		if atomic.LoadInt32(&p.closed) == 1 {
+			return ErrClosed
+		}
+		if atomic.LoadInt32(&p.current) > 0 {
+			return nil
+		}
+		if err := p.notReady(); err != nil {
+			return err
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.done:
+			return ErrClosed
+		}
+	}
+}
+
+// notReady return the error of the last failed dial if it's backing off.
+func (p *pool) notReady() error {
+	p.backoff.Lock()
+	defer p.backoff.Unlock()
+	if p.backoff.failures > 0 && p.backoff.lastErr != nil {
+		return fmt.Errorf("%w: %v", ErrNotReady, p.backoff.lastErr)
+	}
+	return nil
+}
+
+// WaitReady see Pool interface.
+func (p *pool) WaitReady(ctx context.Context) error {
+	if p.opt.InitMode == InitLazy {
+		p.startFill(p.opt.MaxIdle)
+	}
+	for {
+		ch := p.filled.wait()
+		if atomic.LoadInt32(&p.closed) == 1 {
+			return ErrClosed
+		}
+		if atomic.LoadInt32(&p.current) >= p.minReady() {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.done:
+			return ErrClosed
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// blockingDial return a dial blocked until the channel is closed.
+func blockingDial(release chan struct{}, dials *int32) func(string) (*grpc.ClientConn, error) {
+	return func(address string) (*grpc.ClientConn, error) {
+		atomic.AddInt32(dials, 1)
+		<-release
+		return DialTest(address)
+	}
+}
+
+func TestInitAsync(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	opt := DefaultOptions
+	opt.MaxIdle = 2
+	opt.InitMode = InitAsync
+	opt.Dial = blockingDial(release, &dials)
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.current))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, p.WaitReady(ctx))
+	_, err = p.GetContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.ref))
+
+	// the Get waiting for the first connection is served after the dials.
+	done := make(chan error, 1)
+	go func() {
+		conn, err := p.Get()
+		if err == nil {
+			conn.Close()
+		}
+		done <- err
+	}()
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, p.WaitReady(context.Background()))
+	require.EqualValues(t, 2, atomic.LoadInt32(&nativePool.current))
+	require.EqualValues(t, 2, atomic.LoadInt32(&dials))
+}
+
+func TestInitLazy(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	close(release)
+	opt := DefaultOptions
+	opt.MaxIdle = 2
+	opt.InitMode = InitLazy
+	opt.Dial = blockingDial(release, &dials)
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+	require.EqualValues(t, 0, atomic.LoadInt32(&dials))
+
+	conn, err := p.Get()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, p.WaitReady(context.Background()))
+	require.EqualValues(t, 2, atomic.LoadInt32(&nativePool.current))
+	require.EqualValues(t, 2, atomic.LoadInt32(&dials))
+}
+
+func TestInitNotReady(t *testing.T) {
+	opt := DefaultOptions
+	opt.InitMode = InitAsync
+	opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		return nil, errDialTest
+	}
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+
+	// the Get fails fast instead of waiting for the backoff.
+	_, err = p.Get()
+	require.True(t, errors.Is(err, ErrNotReady))
+	require.Contains(t, err.Error(), errDialTest.Error())
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.ref))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.WaitReady(context.Background())
+	}()
+	p.Close()
+	require.Equal(t, ErrClosed, <-done)
+}
+
+func TestMinReady(t *testing.T) {
+	var dials int32
+	opt := DefaultOptions
+	opt.MaxIdle = 3
+	opt.MinReady = 2
+	opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errDialTest
+		}
+		return DialTest(address)
+	}
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the missing one is filled in background.
+	require.True(t, atomic.LoadInt32(&nativePool.current) >= 2)
+	require.NoError(t, p.WaitReady(context.Background()))
+	waitFor(t, func() bool { return atomic.LoadInt32(&nativePool.current) == 3 })
+
+	opt.MinReady = 3
+	atomic.StoreInt32(&dials, 0)
+	_, err = New(*endpoint, opt)
+	require.Error(t, err)
+
+	opt.MinReady = 4
+	_, err = New(*endpoint, opt)
+	require.Error(t, err)
+}
+
+func TestNewContextTimeout(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	defer close(release)
+	opt := DefaultOptions
+	opt.Dial = blockingDial(release, &dials)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := NewContext(ctx, *endpoint, opt)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
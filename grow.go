@@ -0,0 +1,205 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// backoff delays the growth after failed dials, and caches the last dial
+// error for stats.
+type backoff struct {
+	sync.Mutex
+
+	// the number of consecutive failed growths.
+	failures int
+
+	// no growth is attempted until it.
+	until time.Time
+
+	lastErr     error
+	lastErrTime time.Time
+}
+
+// allow return whether the growth is allowed now.
+func (b *backoff) allow() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.failures == 0 || !time.Now().Before(b.until)
+}
+
+// backoffDelay return the delay after the consecutive failures, it's
+// doubled by each failure from GrowBackoffBase up to GrowBackoffMax.
+func backoffDelay(failures int) time.Duration {
+	delay := GrowBackoffBase
+	for i := 0; i < failures && delay < GrowBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > GrowBackoffMax {
+		delay = GrowBackoffMax
+	}
+	return delay
+}
+
+// fail doubles the delay of growth and return it.
+func (b *backoff) fail() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	delay := backoffDelay(b.failures)
+	b.failures++
+	b.until = time.Now().Add(delay)
+	return delay
+}
+
+// reset clears the delay after a successful dial.
+func (b *backoff) reset() {
+	b.Lock()
+	b.failures = 0
+	b.until = time.Time{}
+	b.Unlock()
+}
+
+// dialFailed counts the failed dial and caches its error.
+func (p *pool) dialFailed(err error) {
+	atomic.AddUint64(&p.counters.dialFailures, 1)
+	p.backoff.Lock()
+	p.backoff.lastErr = err
+	p.backoff.lastErrTime = time.Now()
+	p.backoff.Unlock()
+}
+
+// growFailed backs off further growth after the failed dial.
+func (p *pool) growFailed(err error) {
+	delay := p.backoff.fail()
+	p.opt.Logger.Warn("grow pool dial failed", "address", p.address, "error", err, "backoff", delay)
+}
+
+// grow starts growing the pool in background unless it's growing already
+// or backing off after failed dials, and return whether it's started.
+func (p *pool) grow(current int32) bool {
+	if !p.backoff.allow() || !atomic.CompareAndSwapInt32(&p.growing, 0, 1) {
+		return false
+	}
+	go func() {
+		defer atomic.StoreInt32(&p.growing, 0)
+		p.expand(current)
+	}()
+	return true
+}
+
+// expand dials the new connections concurrently without lock, and publishes
+// the dialed ones to the pool at once when all of the dials are done.
+func (p *pool) expand(current int32) {
+	// 2 times the incremental or the remain incremental
+	increment := current
+	if current+increment > p.maxActive() {
+		increment = p.maxActive() - current
+	}
+	if increment <= 0 {
+		return
+	}
+	// reuse the created connections if the budget is exhausted.
+	if increment = int32(p.acquireBudget(int(increment))); increment <= 0 {
+		return
+	}
+
+	dialed, err := p.dial(int(increment))
+	p.releaseBudget(int(increment) - len(dialed))
+	// the dialed ones are published anyway.
+	if err != nil {
+		p.growFailed(err)
+	} else {
+		p.backoff.reset()
+	}
+	if len(dialed) == 0 {
+		return
+	}
+
+	from, to := p.publish(dialed)
+	p.releaseBudget(len(dialed) - int(to-from))
+	if to > from {
+		atomic.AddUint64(&p.counters.grows, 1)
+		p.opt.Logger.Info("grow pool", "from", from, "to", to,
+			"increment", increment, "maxActive", p.maxActive())
+	}
+
+	// the load may be gone during dial.
+	if atomic.LoadInt32(&p.ref) == 0 {
+		p.shrink()
+	}
+}
+
+// dial dials n connections concurrently, the failed dials are counted and
+// the last error is returned with the dialed connections.
+func (p *pool) dial(n int) ([]*grpc.ClientConn, error) {
+	ccs := make([]*grpc.ClientConn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range ccs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ccs[i], errs[i] = p.dialConn()
+		}(i)
+	}
+	wg.Wait()
+
+	var err error
+	dialed := make([]*grpc.ClientConn, 0, n)
+	for i, cc := range ccs {
+		if errs[i] != nil {
+			p.dialFailed(errs[i])
+			err = errs[i]
+			continue
+		}
+		dialed = append(dialed, cc)
+	}
+	return dialed, err
+}
+
+// publish adds the dialed connections to the pool at once and return the
+// range of their slots. The pool may be closed or shrunk during dial, so
+// they're added from the current slot, and the ones beyond MaxActive or
+// dialed after the pool is closed are closed.
+func (p *pool) publish(dialed []*grpc.ClientConn) (from, to int32) {
+	p.Lock()
+	from = atomic.LoadInt32(&p.current)
+	to = from
+	if atomic.LoadInt32(&p.closed) == 0 {
+		for _, cc := range dialed {
+			if to >= p.maxActive() {
+				break
+			}
+			p.reset(int(to))
+			p.conns[to] = p.wrapConn(cc, false)
+			to++
+		}
+		atomic.StoreInt32(&p.current, to)
+	}
+	p.Unlock()
+
+	for _, cc := range dialed[to-from:] {
+		cc.Close()
+	}
+	if to > from {
+		p.filled.broadcast()
+		p.grew(int(from), int(to))
+	}
+	return from, to
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// waitGrow waits for the background growth of the pool to n connections.
+func waitGrow(t *testing.T, p *pool, n int32) {
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&p.current) == n && atomic.LoadInt32(&p.growing) == 0
+	})
+}
+
+func TestGrowBackground(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	opt := DefaultOptions
+	opt.MaxIdle = 2
+	opt.MaxActive = 4
+	opt.MaxConcurrentStreams = 1
+	opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		// the dials of growth are blocked until released.
+		if atomic.AddInt32(&dials, 1) > 2 {
+			<-release
+		}
+		return DialTest(address)
+	}
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the gets beyond the capacity are served by the created connections.
+	conns := make([]Conn, 4)
+	for i := range conns {
+		conns[i], err = p.Get()
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 2, atomic.LoadInt32(&nativePool.current))
+
+	// both of the new connections are dialed concurrently.
+	waitFor(t, func() bool { return atomic.LoadInt32(&dials) == 4 })
+	close(release)
+	waitGrow(t, nativePool, 4)
+	require.EqualValues(t, 1, p.Stats().Grows)
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func TestGrowClosed(t *testing.T) {
+	release := make(chan struct{})
+	opt := DefaultOptions
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Dial = DialTest
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+
+	nativePool.opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		<-release
+		return DialTest(address)
+	}
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	conn2, err := p.Get()
+	require.NoError(t, err)
+
+	// the connection dialed after the pool is closed isn't published.
+	p.Close()
+	close(release)
+	waitFor(t, func() bool { return atomic.LoadInt32(&nativePool.growing) == 0 })
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.current))
+	require.True(t, nativePool.conns[1] == nil)
+	time.Sleep(time.Millisecond)
+
+	conn1.Close()
+	conn2.Close()
+}
+
+// expire ends the backoff of growth.
+func (b *backoff) expire() {
+	b.Lock()
+	b.until = time.Now()
+	b.Unlock()
+}
+
+func TestGrowFailure(t *testing.T) {
+	var dials, fail int32 = 0, 1
+	opt := DefaultOptions
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Dial = DialTest
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	nativePool.opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		atomic.AddInt32(&dials, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			return nil, errDialTest
+		}
+		return DialTest(address)
+	}
+
+	// the failed growth doesn't fail the request.
+	conns := make([]Conn, 0, 4)
+	get := func() {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		conns = append(conns, conn)
+		waitGrow(t, nativePool, atomic.LoadInt32(&nativePool.current))
+	}
+	get()
+	get()
+	s := p.Stats()
+	require.Equal(t, 1, s.Current)
+	require.EqualValues(t, 1, s.DialFailures)
+	require.Equal(t, errDialTest.Error(), s.LastDialError)
+	require.True(t, s.GrowBackoff > 0 && s.GrowBackoff <= GrowBackoffBase)
+
+	// no dial during backoff.
+	get()
+	require.EqualValues(t, 1, atomic.LoadInt32(&dials))
+
+	// the backoff is doubled by the consecutive failure.
+	nativePool.backoff.expire()
+	get()
+	require.EqualValues(t, 2, atomic.LoadInt32(&dials))
+	require.True(t, p.Stats().GrowBackoff > GrowBackoffBase)
+
+	atomic.StoreInt32(&fail, 0)
+	nativePool.backoff.expire()
+	conn, err := p.Get()
+	require.NoError(t, err)
+	conns = append(conns, conn)
+	waitGrow(t, nativePool, 2)
+	s = p.Stats()
+	require.Zero(t, s.GrowBackoff)
+	require.Equal(t, 5, s.Ref)
+
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.ref))
+}
+
+func TestOneTimeDialFailure(t *testing.T) {
+	opt := DefaultOptions
+	opt.MaxIdle = 1
+	opt.MaxActive = 1
+	opt.MaxConcurrentStreams = 1
+	opt.Reuse = false
+	opt.Dial = DialTest
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	nativePool.opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		return nil, errDialTest
+	}
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	require.True(t, conn1.(*handle).c == conn2.(*handle).c)
+	require.EqualValues(t, 0, p.Stats().OneTimeCreated)
+
+	conn1.Close()
+	conn2.Close()
+	require.EqualValues(t, 0, atomic.LoadInt32(&nativePool.ref))
+}
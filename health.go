@@ -0,0 +1,119 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthCheck checks all of the connections every HealthCheckInterval
+// until the pool is closed.
+func (p *pool) healthCheck() {
+	ticker := time.NewTicker(p.opt.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth marks the unhealthy connections and redial them in place.
+// The redialed connection is unhealthy until it's ready, grpc retries it
+// with its own backoff, so the slot is redialed again only after the backoff
+// of the slot. The slots are checked concurrently, so a pass takes about one
+// Check RPC and one dial however many connections are unhealthy.
+func (p *pool) checkHealth() {
+	type slot struct {
+		c  *conn
+		cc *grpc.ClientConn
+	}
+	p.RLock()
+	slots := make([]slot, atomic.LoadInt32(&p.current))
+	for i := range slots {
+		if c := p.conns[i]; c != nil {
+			slots[i] = slot{c: c, cc: c.cc}
+		}
+	}
+	p.RUnlock()
+
+	now := time.Now().UnixNano()
+	var wg sync.WaitGroup
+	for i, s := range slots {
+		if s.cc == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c *conn, cc *grpc.ClientConn) {
+			defer wg.Done()
+			p.checkSlot(i, c, cc, now)
+		}(i, s.c, s.cc)
+	}
+	wg.Wait()
+}
+
+// checkSlot checks the connection of the slot, and redials it if it's
+// unhealthy and not backing off.
+func (p *pool) checkSlot(index int, c *conn, cc *grpc.ClientConn, now int64) {
+	redials := atomic.LoadInt32(&c.redials)
+	if p.isHealthy(cc, redials > 0) {
+		atomic.StoreInt32(&c.unhealthy, 0)
+		atomic.StoreInt32(&c.redials, 0)
+		return
+	}
+	atomic.StoreInt32(&c.unhealthy, 1)
+	if redials > 0 && now < atomic.LoadInt64(&c.redialAt) {
+		return
+	}
+	p.replace(index, c, EvictUnhealthy)
+}
+
+// isHealthy checks the connectivity state and optionally the grpc.health.v1
+// Check RPC of the grpc connection. If ready is true, the connection isn't
+// healthy until it's connected.
+func (p *pool) isHealthy(cc *grpc.ClientConn, ready bool) bool {
+	switch cc.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	case connectivity.Idle:
+		cc.Connect()
+		if ready {
+			return false
+		}
+	case connectivity.Connecting:
+		if ready {
+			return false
+		}
+	}
+	if !p.opt.HealthCheckRPC {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	res, err := healthpb.NewHealthClient(cc).Check(ctx,
+		&healthpb.HealthCheckRequest{Service: p.opt.HealthCheckService})
+	return err == nil && res.GetStatus() == healthpb.HealthCheckResponse_SERVING
+}
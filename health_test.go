@@ -0,0 +1,187 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthCheckState(t *testing.T) {
+	// nothing listens on the address, connections turn to TRANSIENT_FAILURE.
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listen.Addr().String()
+	listen.Close()
+
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	p, err := New(address, opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	old := nativePool.conns[0]
+	waitFor(t, func() bool {
+		nativePool.checkHealth()
+		return nativePool.conns[0] != old
+	})
+	require.False(t, old.healthy())
+
+	// the redialed connection stays unhealthy, and isn't redialed again
+	// until the backoff of the slot.
+	redialed := nativePool.conns[0]
+	for i := 0; i < 10; i++ {
+		nativePool.checkHealth()
+		require.True(t, redialed == nativePool.conns[0])
+		require.False(t, redialed.healthy())
+	}
+	require.EqualValues(t, 1, atomic.LoadInt32(&redialed.redials))
+
+	atomic.StoreInt64(&redialed.redialAt, 0)
+	waitFor(t, func() bool {
+		nativePool.checkHealth()
+		return nativePool.conns[0] != redialed
+	})
+	require.False(t, nativePool.conns[0].healthy())
+	require.EqualValues(t, 2, atomic.LoadInt32(&nativePool.conns[0].redials))
+}
+
+func TestHealthCheckRedialReady(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	go s.Serve(listen)
+	defer s.Stop()
+
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	p, err := New(listen.Addr().String(), opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	// the redialed connection is healthy once it's connected.
+	old := nativePool.conns[0]
+	nativePool.replace(0, old, EvictUnhealthy)
+	redialed := nativePool.conns[0]
+	require.False(t, redialed.healthy())
+	waitFor(t, func() bool {
+		nativePool.checkHealth()
+		return redialed.healthy()
+	})
+	require.True(t, redialed == nativePool.conns[0])
+	require.EqualValues(t, 0, atomic.LoadInt32(&redialed.redials))
+}
+
+func TestHealthCheckRPC(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	hs := health.NewServer()
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(listen)
+	defer s.Stop()
+
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	opt.HealthCheckRPC = true
+	opt.HealthCheckService = "echo"
+	p, err := New(listen.Addr().String(), opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	hs.SetServingStatus("echo", healthpb.HealthCheckResponse_SERVING)
+	old := nativePool.conns[0]
+	nativePool.checkHealth()
+	require.True(t, old.healthy())
+	require.True(t, old == nativePool.conns[0])
+
+	hs.SetServingStatus("echo", healthpb.HealthCheckResponse_NOT_SERVING)
+	nativePool.checkHealth()
+	require.False(t, old.healthy())
+	require.True(t, old != nativePool.conns[0])
+}
+
+// slowHealthServer reports NOT_SERVING after the delay.
+type slowHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	delay time.Duration
+}
+
+func (s *slowHealthServer) Check(ctx context.Context,
+	req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	time.Sleep(s.delay)
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+}
+
+func TestHealthCheckConcurrent(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, &slowHealthServer{delay: 200 * time.Millisecond})
+	go s.Serve(listen)
+	defer s.Stop()
+
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 8
+	opt.MinReady = 8
+	opt.HealthCheckRPC = true
+	opt.HealthCheckInterval = time.Second
+	p, err := New(listen.Addr().String(), opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	// all of the slots are marked within one interval.
+	old := append([]*conn(nil), nativePool.conns[:8]...)
+	start := time.Now()
+	nativePool.checkHealth()
+	require.True(t, time.Since(start) < opt.HealthCheckInterval)
+	for i, c := range old {
+		require.False(t, c.healthy())
+		require.True(t, c != nativePool.conns[i])
+	}
+}
+
+func TestNextSkipUnhealthy(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	nativePool.conns[1].unhealthy = 1
+	for i := 0; i < 4; i++ {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		require.True(t, conn.(*handle).c == nativePool.conns[0])
+		conn.Close()
+	}
+}
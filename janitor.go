@@ -0,0 +1,131 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// janitorInterval return the interval of janitor, which is half of
+// the smallest non-zero IdleTimeout and MaxConnLifetime.
+func (p *pool) janitorInterval() time.Duration {
+	interval := p.opt.IdleTimeout
+	if interval == 0 || (p.opt.MaxConnLifetime > 0 && p.opt.MaxConnLifetime < interval) {
+		interval = p.opt.MaxConnLifetime
+	}
+	if interval /= 2; interval <= 0 {
+		interval = 1
+	}
+	return interval
+}
+
+// janitor retires the idle and aged connections until the pool is closed.
+func (p *pool) janitor() {
+	ticker := time.NewTicker(p.janitorInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.cleanup()
+		}
+	}
+}
+
+// cleanup replaces the aged connections and closes the idle ones beyond MaxIdle.
+func (p *pool) cleanup() {
+	now := time.Now()
+
+	if p.opt.MaxConnLifetime > 0 {
+		var indexes []int
+		var aged []*conn
+		p.RLock()
+		current := int(atomic.LoadInt32(&p.current))
+		for i := 0; i < current; i++ {
+			if c := p.conns[i]; c != nil && !c.expire.IsZero() && now.After(c.expire) {
+				indexes = append(indexes, i)
+				aged = append(aged, c)
+			}
+		}
+		p.RUnlock()
+		for i, c := range aged {
+			p.replace(indexes[i], c, EvictMaxLifetime)
+		}
+	}
+
+	if p.opt.IdleTimeout > 0 {
+		deadline := now.Add(-p.opt.IdleTimeout).UnixNano()
+		p.Lock()
+		current := int(atomic.LoadInt32(&p.current))
+		from := current
+		// move the last connection to the slot of the idle one.
+		for i := current - 1; i >= int(p.maxIdle()); i-- {
+			c := p.conns[i]
+			if c == nil || atomic.LoadInt32(&c.inflight) > 0 ||
+				atomic.LoadInt64(&c.lastUsed) > deadline {
+				continue
+			}
+			current--
+			p.conns[i], p.conns[current] = p.conns[current], nil
+			c.retire()
+		}
+		p.releaseBudget(from - current)
+		if current < from {
+			atomic.StoreInt32(&p.current, int32(current))
+			atomic.AddUint64(&p.counters.shrinks, 1)
+			p.opt.Logger.Info("close idle conns", "from", from, "to", current,
+				"idleTimeout", p.opt.IdleTimeout)
+		}
+		p.Unlock()
+		if current < from {
+			p.shrank(from, current)
+		}
+	}
+}
+
+// replace dials a new connection to replace the old one at the index, the old
+// one is retired gracefully. Nothing is changed if the slot is changed meanwhile.
+func (p *pool) replace(index int, old *conn, reason EvictReason) {
+	cc, err := p.dialConn()
+	if err != nil {
+		p.dialFailed(err)
+		p.opt.Logger.Warn("replace conn failed", "index", index, "address", p.address,
+			"reason", reason, "error", err)
+		return
+	}
+
+	p.Lock()
+	if index >= int(atomic.LoadInt32(&p.current)) || p.conns[index] != old {
+		p.Unlock()
+		cc.Close()
+		return
+	}
+	c := p.wrapConn(cc, false)
+	if reason == EvictUnhealthy {
+		// the redialed connection is unhealthy until it's ready.
+		redials := atomic.LoadInt32(&old.redials)
+		c.unhealthy = 1
+		c.redials = redials + 1
+		c.redialAt = time.Now().Add(backoffDelay(int(redials))).UnixNano()
+	}
+	p.conns[index] = c
+	old.retire()
+	p.Unlock()
+
+	p.opt.Logger.Info("replace conn success", "index", index, "address", p.address, "reason", reason)
+	p.evicted(reason)
+}
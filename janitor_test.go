@@ -0,0 +1,101 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestMaxConnLifetime(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	opt.MaxConnLifetime = time.Millisecond
+	opt.MaxConnLifetimeJitter = time.Millisecond
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// hold a logical connection of the first one.
+	c, err := p.Get()
+	require.NoError(t, err)
+	old := c.(*handle).c
+	time.Sleep(5 * time.Millisecond)
+	nativePool.cleanup()
+
+	nativePool.RLock()
+	require.EqualValues(t, 2, nativePool.current)
+	for _, nc := range nativePool.conns[:2] {
+		require.True(t, nc != old)
+	}
+	nativePool.RUnlock()
+	require.NotEqual(t, connectivity.Shutdown, old.cc.GetState())
+
+	c.Close()
+	require.Equal(t, connectivity.Shutdown, old.cc.GetState())
+}
+
+func TestIdleTimeout(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 3
+	opt.MaxConcurrentStreams = 1
+	opt.IdleTimeout = time.Minute
+	opt.Selector = NewLeastInFlightSelector()
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the pool grows to 1, 2 and 3 connections.
+	conns := make([]Conn, 4)
+	for i := range conns[:3] {
+		conns[i], err = p.Get()
+		require.NoError(t, err)
+		waitGrow(t, nativePool, int32(i+1))
+	}
+	conns[3], err = p.Get()
+	require.NoError(t, err)
+
+	// keep the last one in use, the others are idle.
+	nativePool.RLock()
+	require.EqualValues(t, 3, nativePool.current)
+	busy := nativePool.conns[2]
+	nativePool.RUnlock()
+	require.True(t, conns[3].(*handle).c == busy)
+	for _, c := range conns {
+		if c.(*handle).c != busy {
+			require.NoError(t, c.Close())
+		}
+	}
+	nativePool.RLock()
+	for _, c := range nativePool.conns[:3] {
+		atomic.StoreInt64(&c.lastUsed, time.Now().Add(-time.Hour).UnixNano())
+	}
+	nativePool.RUnlock()
+	nativePool.cleanup()
+
+	nativePool.RLock()
+	require.EqualValues(t, 2, nativePool.current)
+	require.True(t, nativePool.conns[1] == busy)
+	require.True(t, nativePool.conns[2] == nil)
+	nativePool.RUnlock()
+	require.EqualValues(t, 1, p.Stats().Shrinks)
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checkout is an outstanding Conn returned by Get and not closed yet,
+// it's recorded only if Options.LeakThreshold is positive.
+type Checkout struct {
+	// Address is the server address of the pool.
+	Address string
+
+	// Acquired is the time the Conn is returned by Get.
+	Acquired time.Time
+
+	// Age is the duration since the Conn is returned by Get.
+	Age time.Duration
+
+	// Stack is the stack trace of the goroutine calling Get.
+	Stack string
+}
+
+// tracker records the outstanding handles for leak detection.
+type tracker struct {
+	sync.Mutex
+	handles map[*handle]struct{}
+}
+
+func (t *tracker) add(h *handle) {
+	t.Lock()
+	if t.handles == nil {
+		t.handles = make(map[*handle]struct{})
+	}
+	t.handles[h] = struct{}{}
+	t.Unlock()
+}
+
+func (t *tracker) remove(h *handle) {
+	t.Lock()
+	delete(t.handles, h)
+	t.Unlock()
+}
+
+// stack return the stack trace of the caller goroutine.
+func stack() string {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// track records the handle with the stack of the caller.
+func (p *pool) track(h *handle) {
+	h.acquired = time.Now()
+	h.stack = stack()
+	p.tracker.add(h)
+}
+
+// Checkouts see Pool interface.
+func (p *pool) Checkouts() []Checkout {
+	now := time.Now()
+	p.tracker.Lock()
+	checkouts := make([]Checkout, 0, len(p.tracker.handles))
+	for h := range p.tracker.handles {
+		checkouts = append(checkouts, Checkout{
+			Address:  p.address,
+			Acquired: h.acquired,
+			Age:      now.Sub(h.acquired),
+			Stack:    h.stack,
+		})
+	}
+	p.tracker.Unlock()
+
+	sort.Slice(checkouts, func(i, j int) bool {
+		return checkouts[i].Acquired.Before(checkouts[j].Acquired)
+	})
+	return checkouts
+}
+
+// leakCheck reports the leaks every half of LeakThreshold until the pool is closed.
+func (p *pool) leakCheck() {
+	ticker := time.NewTicker(p.opt.LeakThreshold/2 + 1)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkLeaks()
+		}
+	}
+}
+
+// checkLeaks reports the handles older than LeakThreshold, each one is reported once.
+func (p *pool) checkLeaks() {
+	now := time.Now()
+	var leaks []Checkout
+	p.tracker.Lock()
+	for h := range p.tracker.handles {
+		if h.reported || now.Sub(h.acquired) < p.opt.LeakThreshold {
+			continue
+		}
+		h.reported = true
+		leaks = append(leaks, Checkout{
+			Address:  p.address,
+			Acquired: h.acquired,
+			Age:      now.Sub(h.acquired),
+			Stack:    h.stack,
+		})
+	}
+	p.tracker.Unlock()
+
+	for _, leak := range leaks {
+		p.opt.Logger.Warn("conn leak suspected", "address", leak.Address,
+			"age", leak.Age, "stack", leak.Stack)
+		if p.opt.OnLeak != nil {
+			p.opt.OnLeak(leak)
+		}
+	}
+}
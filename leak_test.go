@@ -0,0 +1,87 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeakDetection(t *testing.T) {
+	var mu sync.Mutex
+	var leaks []Checkout
+
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.LeakThreshold = 50 * time.Millisecond
+	opt.Logger = NopLogger
+	opt.OnLeak = func(c Checkout) {
+		mu.Lock()
+		leaks = append(leaks, c)
+		mu.Unlock()
+	}
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	leaked, err := p.Get()
+	require.NoError(t, err)
+	closed, err := p.Get()
+	require.NoError(t, err)
+
+	checkouts := p.Checkouts()
+	require.Len(t, checkouts, 2)
+	require.True(t, strings.Contains(checkouts[0].Stack, "TestLeakDetection"))
+	require.Equal(t, nativePool.address, checkouts[0].Address)
+
+	closed.Close()
+	require.Len(t, p.Checkouts(), 1)
+
+	// nothing is older than the threshold.
+	nativePool.checkLeaks()
+	require.Empty(t, leaks)
+
+	// the background check reports the leak only once.
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(leaks) > 0
+	})
+	nativePool.checkLeaks()
+	mu.Lock()
+	require.Len(t, leaks, 1)
+	require.True(t, strings.Contains(leaks[0].Stack, "TestLeakDetection"))
+	mu.Unlock()
+
+	leaked.Close()
+	require.Empty(t, p.Checkouts())
+}
+
+func TestLeakDetectionDisabled(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	p, _, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conn, err := p.Get()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Empty(t, p.Checkouts())
+}
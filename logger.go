@@ -0,0 +1,120 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is a leveled and structured logger used by the pool.
+// The keyvals are alternating keys and values, the same as log/slog.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NopLogger discards all of the logs.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// defaultLogger is used if Options.Logger is nil.
+var defaultLogger = NewStdLogger(log.Default())
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger return a logger writes to the standard library logger in
+// the format "msg: key1=value1, key2=value2", the pool events logged before
+// Logger is added keep their original format. The debug logs are discarded.
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+// legacyFormats are the formats of the events logged by log.Printf before
+// Logger is added, the keys are filled in order.
+var legacyFormats = map[string]struct {
+	format string
+	keys   []string
+}{
+	"new pool success":   {"new pool success: %v", []string{"status"}},
+	"close pool success": {"close pool success: %v", []string{"status"}},
+	"grow pool":          {"grow pool: %v ---> %v, increment: %v, maxActive: %v", []string{"from", "to", "increment", "maxActive"}},
+	"shrink pool":        {"shrink pool: %v ---> %v, decrement: %v, maxActive: %v", []string{"from", "to", "decrement", "maxActive"}},
+}
+
+// legacy renders the event in the format before Logger is added, so that
+// the output of the default logger is unchanged. It return false if the
+// event isn't one of them or the keys don't match.
+func legacy(msg string, keyvals []interface{}) (string, bool) {
+	f, ok := legacyFormats[msg]
+	if !ok || len(keyvals) != 2*len(f.keys) {
+		return "", false
+	}
+	args := make([]interface{}, len(f.keys))
+	for i, key := range f.keys {
+		if keyvals[2*i] != key {
+			return "", false
+		}
+		args[i] = keyvals[2*i+1]
+	}
+	return fmt.Sprintf(f.format, args...), true
+}
+
+func (s *stdLogger) output(msg string, keyvals []interface{}) {
+	if line, ok := legacy(msg, keyvals); ok {
+		s.l.Output(3, line)
+		return
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&b, "%v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&b, "%v", keyvals[i])
+		}
+	}
+	s.l.Output(3, b.String())
+}
+
+func (s *stdLogger) Debug(string, ...interface{}) {}
+
+func (s *stdLogger) Info(msg string, keyvals ...interface{}) {
+	s.output(msg, keyvals)
+}
+
+func (s *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	s.output(msg, keyvals)
+}
+
+func (s *stdLogger) Error(msg string, keyvals ...interface{}) {
+	s.output(msg, keyvals)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0))
+
+	l.Info("grow pool", "from", 1, "to", 2)
+	require.Equal(t, "grow pool: from=1, to=2\n", buf.String())
+
+	buf.Reset()
+	l.Warn("odd", "key")
+	require.Equal(t, "odd: key\n", buf.String())
+
+	buf.Reset()
+	l.Info("shrink pool", "from", 4, "to", 2, "decrement", 2, "maxActive", 64)
+	require.Equal(t, "shrink pool: 4 ---> 2, decrement: 2, maxActive: 64\n", buf.String())
+
+	buf.Reset()
+	l.Debug("ignored", "key", "value")
+	require.Empty(t, buf.String())
+}
+
+func TestPoolLogger(t *testing.T) {
+	var buf bytes.Buffer
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Logger = NewStdLogger(log.New(&buf, "", 0))
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "new pool success: address:"+*endpoint)
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	waitGrow(t, nativePool, 2)
+	require.Contains(t, buf.String(), "grow pool: 1 ---> 2, increment: 1, maxActive: 2\n")
+
+	conn1.Close()
+	conn2.Close()
+	require.Contains(t, buf.String(), "shrink pool: 2 ---> 1, decrement: 1, maxActive: 2\n")
+
+	p.Close()
+	require.Contains(t, buf.String(), "close pool success: address:"+*endpoint)
+}
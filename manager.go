@@ -0,0 +1,322 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ManagerOptions are params for creating a Manager.
+type ManagerOptions struct {
+	// Options is the default options of the pools.
+	Options Options
+
+	// Overrides are the options of the specific addresses instead of the default.
+	Overrides map[string]Options
+
+	// IdleTTL closes the pools which have no Get and outstanding Conn for
+	// longer than it. Zero disables it.
+	IdleTTL time.Duration
+
+	// MaxPools caps the number of pools, the least recently used one is
+	// evicted when a pool is created beyond it. Zero means no limit.
+	MaxPools int
+
+	// ShutdownTimeout is the time to wait for the outstanding Conns of
+	// an evicted pool before it's closed. Zero closes it immediately.
+	ShutdownTimeout time.Duration
+}
+
+// Manager lazily creates a Pool per address and manages their lifetime.
+type Manager interface {
+	// Pool returns the pool of the address, it's created on the first call.
+	Pool(address string) (Pool, error)
+
+	// Remove removes the pool of the address from the manager, the pool is
+	// closed after the outstanding Conns are closed or ShutdownTimeout.
+	Remove(address string) error
+
+	// Addresses returns the addresses of the created pools.
+	Addresses() []string
+
+	// Close closes all of the pools, Pool() returns ErrClosed after it.
+	Close() error
+}
+
+// entry is the pool of an address, ready is closed when New is returned.
+type entry struct {
+	address string
+	ready   chan struct{}
+	pool    *pool
+	err     error
+
+	// the time of the last use and the Get count seen at that time.
+	lastUsed time.Time
+	gets     uint64
+}
+
+type manager struct {
+	opt ManagerOptions
+
+	// closed set true when Close is called.
+	closed int32
+
+	// done is closed when Close is called to stop the janitor.
+	done chan struct{}
+
+	// protect the entries, lru front is the most recently used one.
+	sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+}
+
+// NewManager return a manager creating the pools with the option.
+func NewManager(option ManagerOptions) Manager {
+	// the overrides are read without lock, don't share them with the caller.
+	overrides := make(map[string]Options, len(option.Overrides))
+	for address, opt := range option.Overrides {
+		overrides[address] = opt
+	}
+	option.Overrides = overrides
+
+	m := &manager{
+		opt:     option,
+		done:    make(chan struct{}),
+		entries: make(map[string]*list.Element),
+	}
+	if m.opt.IdleTTL > 0 {
+		go m.janitor()
+	}
+	return m
+}
+
+// options return the pool options of the address.
+func (m *manager) options(address string) Options {
+	if opt, ok := m.opt.Overrides[address]; ok {
+		return opt
+	}
+	return m.opt.Options
+}
+
+// Pool see Manager interface.
+func (m *manager) Pool(address string) (Pool, error) {
+	m.Lock()
+	if atomic.LoadInt32(&m.closed) == 1 {
+		m.Unlock()
+		return nil, ErrClosed
+	}
+	if elem, ok := m.entries[address]; ok {
+		m.lru.MoveToFront(elem)
+		e := elem.Value.(*entry)
+		e.lastUsed = time.Now()
+		m.Unlock()
+
+		// wait for the pool created by the first caller.
+		return m.wait(e)
+	}
+
+	e := &entry{address: address, ready: make(chan struct{}), lastUsed: time.Now()}
+	m.entries[address] = m.lru.PushFront(e)
+	evicted := m.evictLRU()
+	m.Unlock()
+
+	for _, old := range evicted {
+		m.shutdown(old, EvictLRU)
+	}
+
+	// dial without lock, the others wait for it by ready.
+	p, err := New(address, m.options(address))
+	if err == nil {
+		e.pool = p.(*pool)
+	}
+	e.err = err
+	close(e.ready)
+
+	if err != nil {
+		m.Lock()
+		if elem, ok := m.entries[address]; ok && elem.Value.(*entry) == e {
+			delete(m.entries, address)
+			m.lru.Remove(elem)
+		}
+		m.Unlock()
+		return nil, err
+	}
+	return m.wait(e)
+}
+
+// wait return the pool of the entry once it's created, ErrClosed if the
+// entry is evicted, removed or the manager is closed during dial, the
+// pool is closed by them.
+func (m *manager) wait(e *entry) (Pool, error) {
+	<-e.ready
+	if e.err != nil {
+		return nil, e.err
+	}
+	m.Lock()
+	elem, ok := m.entries[e.address]
+	m.Unlock()
+	if !ok || elem.Value.(*entry) != e {
+		return nil, ErrClosed
+	}
+	return e.pool, nil
+}
+
+// evictLRU removes the least recently used entries beyond MaxPools,
+// it must be called with lock held.
+func (m *manager) evictLRU() []*entry {
+	var evicted []*entry
+	for m.opt.MaxPools > 0 && m.lru.Len() > m.opt.MaxPools {
+		elem := m.lru.Back()
+		e := elem.Value.(*entry)
+		m.lru.Remove(elem)
+		delete(m.entries, e.address)
+		evicted = append(evicted, e)
+	}
+	return evicted
+}
+
+// shutdown closes the pool of the evicted entry, the outstanding Conns
+// are waited for ShutdownTimeout in background.
+func (m *manager) shutdown(e *entry, reason EvictReason) {
+	go func() {
+		<-e.ready
+		if e.pool == nil {
+			return
+		}
+		e.pool.opt.Observer.OnEvict(e.address, reason)
+		if m.opt.ShutdownTimeout <= 0 {
+			e.pool.Close()
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), m.opt.ShutdownTimeout)
+		defer cancel()
+		e.pool.Shutdown(ctx)
+	}()
+}
+
+// janitor evicts the idle pools every half of IdleTTL until the manager is closed.
+func (m *manager) janitor() {
+	ticker := time.NewTicker(m.opt.IdleTTL/2 + 1)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.evictIdle()
+		}
+	}
+}
+
+// evictIdle removes the pools which have no Get and outstanding Conn for IdleTTL,
+// the Gets on the Pool returned before are counted as use too.
+func (m *manager) evictIdle() {
+	now := time.Now()
+	var evicted []*entry
+	m.Lock()
+	for elem := m.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		e := elem.Value.(*entry)
+		select {
+		case <-e.ready:
+		default:
+			// still dialing.
+			elem = prev
+			continue
+		}
+		if e.pool == nil {
+			elem = prev
+			continue
+		}
+		if gets := atomic.LoadUint64(&e.pool.counters.gets); gets != e.gets {
+			e.gets, e.lastUsed = gets, now
+		}
+		if atomic.LoadInt32(&e.pool.ref) == 0 && now.Sub(e.lastUsed) >= m.opt.IdleTTL {
+			m.lru.Remove(elem)
+			delete(m.entries, e.address)
+			evicted = append(evicted, e)
+		}
+		elem = prev
+	}
+	m.Unlock()
+
+	for _, e := range evicted {
+		e.pool.opt.Logger.Info("evict idle pool", "address", e.address, "idleTTL", m.opt.IdleTTL)
+		m.shutdown(e, EvictIdleTTL)
+	}
+}
+
+// Remove see Manager interface.
+func (m *manager) Remove(address string) error {
+	m.Lock()
+	elem, ok := m.entries[address]
+	if !ok {
+		m.Unlock()
+		return fmt.Errorf("address %s doesn't exist", address)
+	}
+	e := elem.Value.(*entry)
+	m.lru.Remove(elem)
+	delete(m.entries, address)
+	m.Unlock()
+
+	// no more Conn is returned by the pool, it's drained the same as eviction.
+	<-e.ready
+	if e.pool != nil {
+		e.pool.stop()
+	}
+	m.shutdown(e, EvictRemove)
+	return nil
+}
+
+// Addresses see Manager interface.
+func (m *manager) Addresses() []string {
+	m.Lock()
+	addresses := make([]string, 0, len(m.entries))
+	for address := range m.entries {
+		addresses = append(addresses, address)
+	}
+	m.Unlock()
+	sort.Strings(addresses)
+	return addresses
+}
+
+// Close see Manager interface.
+func (m *manager) Close() error {
+	m.Lock()
+	if !atomic.CompareAndSwapInt32(&m.closed, 0, 1) {
+		m.Unlock()
+		return nil
+	}
+	close(m.done)
+	entries := m.entries
+	m.entries = make(map[string]*list.Element)
+	m.lru.Init()
+	m.Unlock()
+
+	for _, elem := range entries {
+		e := elem.Value.(*entry)
+		<-e.ready
+		if e.pool != nil {
+			e.pool.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+var errDialTest = errors.New("dial test error")
+
+func newManagerOptions() ManagerOptions {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	return ManagerOptions{Options: opt}
+}
+
+func TestManagerPool(t *testing.T) {
+	mopt := newManagerOptions()
+	override := mopt.Options
+	override.MaxIdle = 2
+	mopt.Overrides = map[string]Options{addresses[1]: override}
+	m := NewManager(mopt)
+
+	var wg sync.WaitGroup
+	pools := make([]Pool, 8)
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p, err := m.Pool(addresses[0])
+			require.NoError(t, err)
+			pools[i] = p
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range pools {
+		require.True(t, p == pools[0])
+	}
+
+	p, err := m.Pool(addresses[1])
+	require.NoError(t, err)
+	require.Equal(t, 2, p.Stats().Current)
+	require.Equal(t, 1, pools[0].Stats().Current)
+	require.Equal(t, addresses[:2], m.Addresses())
+
+	require.Error(t, m.Remove(addresses[2]))
+	require.NoError(t, m.Remove(addresses[1]))
+	require.Equal(t, addresses[:1], m.Addresses())
+	_, err = p.Get()
+	require.Equal(t, ErrClosed, err)
+	waitFor(t, func() bool {
+		return p.Stats().Current == 0
+	})
+
+	require.NoError(t, m.Close())
+	require.Empty(t, m.Addresses())
+	_, err = pools[0].Get()
+	require.Equal(t, ErrClosed, err)
+	_, err = m.Pool(addresses[0])
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestManagerRemoveDrain(t *testing.T) {
+	r := &recorder{}
+	mopt := newManagerOptions()
+	mopt.Options.Observer = r
+	mopt.ShutdownTimeout = time.Hour
+	m := NewManager(mopt)
+	defer m.Close()
+
+	p, err := m.Pool(addresses[0])
+	require.NoError(t, err)
+	conn, err := p.Get()
+	require.NoError(t, err)
+
+	// the outstanding conn is still usable until it's closed.
+	require.NoError(t, m.Remove(addresses[0]))
+	require.Empty(t, m.Addresses())
+	_, err = p.Get()
+	require.Equal(t, ErrClosed, err)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, 1, p.Stats().Current)
+
+	require.NoError(t, conn.Close())
+	waitFor(t, func() bool {
+		return p.Stats().Current == 0
+	})
+	require.Contains(t, r.recorded(), "evict "+string(EvictRemove))
+}
+
+func TestManagerEvictDialing(t *testing.T) {
+	dialing, release := make(chan struct{}), make(chan struct{})
+	mopt := newManagerOptions()
+	mopt.MaxPools = 1
+	mopt.Options.Dial = func(address string) (*grpc.ClientConn, error) {
+		if address == addresses[0] {
+			close(dialing)
+			<-release
+		}
+		return DialTest(address)
+	}
+	m := NewManager(mopt)
+	defer m.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := m.Pool(addresses[0])
+		errs <- err
+	}()
+	<-dialing
+
+	// the dialing pool is evicted by the new one.
+	_, err := m.Pool(addresses[1])
+	require.NoError(t, err)
+	require.Equal(t, addresses[1:2], m.Addresses())
+	close(release)
+	require.Equal(t, ErrClosed, <-errs)
+}
+
+func TestManagerDialError(t *testing.T) {
+	var dials int32
+	mopt := newManagerOptions()
+	mopt.Options.Dial = func(address string) (*grpc.ClientConn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errDialTest
+		}
+		return DialTest(address)
+	}
+	m := NewManager(mopt)
+	defer m.Close()
+
+	_, err := m.Pool(addresses[0])
+	require.Error(t, err)
+	require.Empty(t, m.Addresses())
+
+	_, err = m.Pool(addresses[0])
+	require.NoError(t, err)
+}
+
+func TestManagerLRU(t *testing.T) {
+	mopt := newManagerOptions()
+	mopt.MaxPools = 2
+	m := NewManager(mopt)
+	defer m.Close()
+
+	first, err := m.Pool(addresses[0])
+	require.NoError(t, err)
+	_, err = m.Pool(addresses[1])
+	require.NoError(t, err)
+
+	// touch the first one, the second one is the least recently used.
+	_, err = m.Pool(addresses[0])
+	require.NoError(t, err)
+	_, err = m.Pool(addresses[2])
+	require.NoError(t, err)
+	require.Equal(t, []string{addresses[0], addresses[2]}, m.Addresses())
+
+	conn, err := first.Get()
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestManagerIdleTTL(t *testing.T) {
+	mopt := newManagerOptions()
+	mopt.IdleTTL = time.Hour
+	m := NewManager(mopt)
+	defer m.Close()
+	nativeManager := m.(*manager)
+
+	busy, err := m.Pool(addresses[0])
+	require.NoError(t, err)
+	idle, err := m.Pool(addresses[1])
+	require.NoError(t, err)
+
+	conn, err := busy.Get()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	nativeManager.evictIdle()
+	require.Equal(t, addresses[:2], m.Addresses())
+
+	nativeManager.Lock()
+	for _, elem := range nativeManager.entries {
+		elem.Value.(*entry).lastUsed = time.Now().Add(-2 * time.Hour)
+	}
+	nativeManager.Unlock()
+
+	// the busy one has an outstanding conn.
+	nativeManager.evictIdle()
+	require.Equal(t, addresses[:1], m.Addresses())
+	waitFor(t, func() bool {
+		_, err := idle.Get()
+		return err == ErrClosed
+	})
+}
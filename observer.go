@@ -0,0 +1,104 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// EvictReason is the reason of an eviction reported by Observer.OnEvict.
+type EvictReason string
+
+const (
+	// EvictMaxLifetime is a connection replaced after MaxConnLifetime.
+	EvictMaxLifetime EvictReason = "max lifetime"
+
+	// EvictUnhealthy is a connection replaced by health checking.
+	EvictUnhealthy EvictReason = "unhealthy"
+
+	// EvictCertRotation is a connection replaced after TLSFiles are reloaded.
+	EvictCertRotation EvictReason = "cert rotation"
+
+	// EvictIdleTTL is a pool closed by Manager after IdleTTL.
+	EvictIdleTTL EvictReason = "idle ttl"
+
+	// EvictLRU is a pool closed by Manager beyond MaxPools.
+	EvictLRU EvictReason = "lru"
+
+	// EvictRemove is a pool removed by Manager.Remove.
+	EvictRemove EvictReason = "remove"
+)
+
+// Observer receives the lifecycle events of a pool. The methods are called
+// synchronously without lock held, they should return quickly.
+type Observer interface {
+	// OnDial is called after each dial with its error and latency.
+	OnDial(address string, err error, latency time.Duration)
+
+	// OnGrow is called when connections are added to the pool.
+	OnGrow(address string, from, to int)
+
+	// OnShrink is called when connections are removed from the pool.
+	OnShrink(address string, from, to int)
+
+	// OnOneTimeConn is called when a one-time connection is created.
+	OnOneTimeConn(address string)
+
+	// OnEvict is called when a connection is replaced, or the pool is
+	// evicted by Manager.
+	OnEvict(address string, reason EvictReason)
+
+	// OnClose is called once the pool is closed.
+	OnClose(address string)
+}
+
+// NopObserver ignores all of the events, it can be embedded to implement
+// the part of Observer needed.
+type NopObserver struct{}
+
+func (NopObserver) OnDial(string, error, time.Duration) {}
+func (NopObserver) OnGrow(string, int, int)             {}
+func (NopObserver) OnShrink(string, int, int)           {}
+func (NopObserver) OnOneTimeConn(string)                {}
+func (NopObserver) OnEvict(string, EvictReason)         {}
+func (NopObserver) OnClose(string)                      {}
+
+// grew reports the growth to the observer and the debug events.
+func (p *pool) grew(from, to int) {
+	p.events.add(Event{Kind: "grow", From: from, To: to})
+	p.opt.Observer.OnGrow(p.address, from, to)
+}
+
+// shrank reports the shrink to the observer and the debug events.
+func (p *pool) shrank(from, to int) {
+	p.events.add(Event{Kind: "shrink", From: from, To: to})
+	p.opt.Observer.OnShrink(p.address, from, to)
+}
+
+// evicted reports the replaced connection to the observer and the debug events.
+func (p *pool) evicted(reason EvictReason) {
+	p.events.add(Event{Kind: "evict", Reason: string(reason)})
+	p.opt.Observer.OnEvict(p.address, reason)
+}
+
+// dialConn dials a connection by Options.Dial and reports it to the observer.
+func (p *pool) dialConn() (*grpc.ClientConn, error) {
+	start := time.Now()
+	cc, err := p.opt.Dial(p.address)
+	p.opt.Observer.OnDial(p.address, err, time.Since(start))
+	return cc, err
+}
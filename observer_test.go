@@ -0,0 +1,116 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type recorder struct {
+	NopObserver
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(format string, args ...interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+	r.mu.Unlock()
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *recorder) OnDial(address string, err error, latency time.Duration) {
+	r.record("dial %v", err)
+}
+
+func (r *recorder) OnGrow(address string, from, to int) {
+	r.record("grow %d %d", from, to)
+}
+
+func (r *recorder) OnShrink(address string, from, to int) {
+	r.record("shrink %d %d", from, to)
+}
+
+func (r *recorder) OnOneTimeConn(address string) {
+	r.record("one-time")
+}
+
+func (r *recorder) OnClose(address string) {
+	r.record("close")
+}
+
+func (r *recorder) OnEvict(address string, reason EvictReason) {
+	r.record("evict %s", reason)
+}
+
+func TestObserver(t *testing.T) {
+	r := &recorder{}
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Reuse = false
+	opt.Observer = r
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	waitGrow(t, nativePool, 2)
+	conn3, err := p.Get()
+	require.NoError(t, err)
+
+	conn1.Close()
+	conn2.Close()
+	conn3.Close()
+	p.Close()
+	p.Close()
+
+	require.Equal(t, []string{
+		"dial <nil>", "grow 0 1",
+		"dial <nil>", "grow 1 2",
+		"dial <nil>", "one-time",
+		"shrink 2 1",
+		"close",
+	}, r.recorded())
+}
+
+func TestObserverDialError(t *testing.T) {
+	r := &recorder{}
+	opt := DefaultOptions
+	opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		return nil, errDialTest
+	}
+	opt.MaxIdle = 1
+	opt.Observer = r
+	_, err := New(*endpoint, opt)
+	require.Error(t, err)
+	require.Equal(t, []string{"dial " + errDialTest.Error(), "close"}, r.recorded())
+}
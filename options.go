@@ -16,10 +16,10 @@ package pool
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/keepalive"
 )
 
 const (
@@ -51,16 +51,66 @@ const (
 	// MaxRecvMsgSize set max gRPC receive message size received from server.
 	// If any message size is larger than current value, an error will be reported from gRPC.
 	MaxRecvMsgSize = 4 << 30
+
+	// HealthCheckTimeout the timeout of grpc.health.v1 Check RPC.
+	HealthCheckTimeout = 3 * time.Second
+
+	// GrowBackoffBase is the delay of growth after the first failed dial,
+	// it's doubled by each consecutive failure.
+	GrowBackoffBase = 100 * time.Millisecond
+
+	// GrowBackoffMax is the maximum delay of growth after failed dials.
+	GrowBackoffMax = 30 * time.Second
+
+	// ShrinkInterval is the default interval to evaluate the ScalingPolicy.
+	ShrinkInterval = time.Second
+)
+
+// InitMode is the way the pool dials MaxIdle connections when it's created.
+type InitMode int
+
+const (
+	// InitSync dials the connections before New returns, it's the default.
+	InitSync InitMode = iota
+
+	// InitAsync returns immediately and dials the connections in background.
+	InitAsync
+
+	// InitLazy dials the connections on the first Get or WaitReady.
+	InitLazy
 )
 
+func (m InitMode) String() string {
+	switch m {
+	case InitSync:
+		return "sync"
+	case InitAsync:
+		return "async"
+	case InitLazy:
+		return "lazy"
+	}
+	return fmt.Sprintf("InitMode(%d)", int(m))
+}
+
 // Options are params for creating grpc connect pool.
 type Options struct {
 	// Dial is an application supplied function for creating and configuring a connection.
 	Dial func(address string) (*grpc.ClientConn, error)
 
+	// DialConfig builds the Dial of the pool from the profile if it's not
+	// nil, it takes precedence over Dial.
+	DialConfig *DialConfig
+
 	// Maximum number of idle connections in the pool.
 	MaxIdle int
 
+	// MinReady is the number of connections enough for the pool to be ready,
+	// InitSync fails if less connections are dialed. Zero means MaxIdle.
+	MinReady int
+
+	// InitMode is the way the pool dials MaxIdle connections when it's created.
+	InitMode InitMode
+
 	// Maximum number of connections allocated by the pool at a given time.
 	// When zero, there is no limit on the number of connections in the pool.
 	MaxActive int
@@ -72,6 +122,83 @@ type Options struct {
 	// the connection to return, If Reuse is false and the pool is at the MaxActive limit,
 	// create a one-time connection to return.
 	Reuse bool
+
+	// If Wait is true and the pool is at the MaxActive limit with all of the
+	// logical connections in use, then Get() waits in FIFO order for a logical
+	// connection to be given back to the pool. Wait takes precedence over Reuse.
+	Wait bool
+
+	// HealthCheckInterval is the interval of checking the connectivity state of
+	// each connection. The unhealthy connections are skipped by Get() and
+	// redialed in place. When zero, health checking is disabled.
+	HealthCheckInterval time.Duration
+
+	// If HealthCheckRPC is true, health checking also calls the standard
+	// grpc.health.v1 Check RPC with HealthCheckService, the connection is
+	// unhealthy unless the server replies SERVING.
+	HealthCheckRPC bool
+
+	// HealthCheckService is the service name of grpc.health.v1 Check RPC.
+	// When empty, the overall health of the server is checked.
+	HealthCheckService string
+
+	// Selector selects the physical connection for each Get() from the healthy
+	// connections. When nil, the connections are selected by round-robin.
+	Selector Selector
+
+	// IdleTimeout closes the connections beyond MaxIdle which have no logical
+	// connection in use for the duration. When zero, they are closed only if
+	// none of the logical connections of the pool is in use.
+	IdleTimeout time.Duration
+
+	// MaxConnLifetime is the maximum duration a connection may be reused,
+	// the aged connections are replaced with new ones gracefully, the old ones
+	// are closed after their logical connections are closed. When zero,
+	// connections are reused forever.
+	MaxConnLifetime time.Duration
+
+	// MaxConnLifetimeJitter adds a random duration in [0, jitter) to the
+	// MaxConnLifetime of each connection, so that they are not replaced at once.
+	MaxConnLifetimeJitter time.Duration
+
+	// LeakThreshold enables leak detection if it's positive. The stack of each
+	// Get() is recorded, the Conns which are not closed for longer than the
+	// threshold are reported once by Logger and OnLeak.
+	LeakThreshold time.Duration
+
+	// OnLeak is called with each suspected leak if it's not nil.
+	OnLeak func(Checkout)
+
+	// Budget caps the physical connections shared with other pools if it's
+	// not nil. When it's exhausted, the pool reuses the connections instead
+	// of dialing, or waits for the budget if Wait is true.
+	Budget *Budget
+
+	// Logger receives the logs of pool events such as grow and shrink.
+	// When nil, the logs are written to the standard library logger,
+	// use NopLogger to silence them.
+	Logger Logger
+
+	// Observer receives the lifecycle events of the pool such as dial, grow
+	// and shrink. When nil, the events are only logged.
+	Observer Observer
+
+	// ScalingPolicy decides how many surplus connections beyond MaxIdle are
+	// retired. When nil, all of them are closed once the ref drops to zero.
+	ScalingPolicy ScalingPolicy
+
+	// ShrinkInterval is the interval to evaluate ScalingPolicy, zero means
+	// the default ShrinkInterval.
+	ShrinkInterval time.Duration
+
+	// Balancer picks the sub-pool for each Get() of the pool created by
+	// NewCluster. When nil, the sub-pools are picked by round-robin.
+	Balancer Balancer
+
+	// RemoveTimeout is the time Cluster.Remove waits for the outstanding
+	// Conns of the removed sub-pool before it's closed. When zero, it waits
+	// until all of them are closed.
+	RemoveTimeout time.Duration
 }
 
 // DefaultOptions sets a list of recommended options for good performance.
@@ -84,21 +211,141 @@ var DefaultOptions = Options{
 	Reuse:                true,
 }
 
-// Dial return a grpc connection with defined configurations.
+// Option sets a field of Options, it's applied by NewOptions.
+type Option func(*Options)
+
+// NewOptions return the options with the opts applied, and validates them.
+// The Dial and limits not set by opts are defaulted by WithDefaults, the
+// others are the same as DefaultOptions.
+func NewOptions(opts ...Option) (Options, error) {
+	opt := Options{Reuse: DefaultOptions.Reuse}
+	for _, o := range opts {
+		o(&opt)
+	}
+	opt = opt.WithDefaults()
+	return opt, opt.Validate()
+}
+
+// WithDialer sets Options.Dial.
+func WithDialer(dial func(address string) (*grpc.ClientConn, error)) Option {
+	return func(o *Options) { o.Dial = dial }
+}
+
+// WithDialConfig sets Options.DialConfig.
+func WithDialConfig(config DialConfig) Option {
+	return func(o *Options) { o.DialConfig = &config }
+}
+
+// WithMaxIdle sets Options.MaxIdle.
+func WithMaxIdle(maxIdle int) Option {
+	return func(o *Options) { o.MaxIdle = maxIdle }
+}
+
+// WithMinReady sets Options.MinReady.
+func WithMinReady(minReady int) Option {
+	return func(o *Options) { o.MinReady = minReady }
+}
+
+// WithInitMode sets Options.InitMode.
+func WithInitMode(mode InitMode) Option {
+	return func(o *Options) { o.InitMode = mode }
+}
+
+// WithMaxActive sets Options.MaxActive.
+func WithMaxActive(maxActive int) Option {
+	return func(o *Options) { o.MaxActive = maxActive }
+}
+
+// WithMaxConcurrentStreams sets Options.MaxConcurrentStreams.
+func WithMaxConcurrentStreams(maxConcurrentStreams int) Option {
+	return func(o *Options) { o.MaxConcurrentStreams = maxConcurrentStreams }
+}
+
+// WithReuse sets Options.Reuse.
+func WithReuse(reuse bool) Option {
+	return func(o *Options) { o.Reuse = reuse }
+}
+
+// WithWait sets Options.Wait.
+func WithWait(wait bool) Option {
+	return func(o *Options) { o.Wait = wait }
+}
+
+// WithHealthCheck sets Options.HealthCheckInterval.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(o *Options) { o.HealthCheckInterval = interval }
+}
+
+// WithHealthCheckRPC enables Options.HealthCheckRPC with the service.
+func WithHealthCheckRPC(service string) Option {
+	return func(o *Options) {
+		o.HealthCheckRPC = true
+		o.HealthCheckService = service
+	}
+}
+
+// WithSelector sets Options.Selector.
+func WithSelector(selector Selector) Option {
+	return func(o *Options) { o.Selector = selector }
+}
+
+// WithIdleTimeout sets Options.IdleTimeout.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *Options) { o.IdleTimeout = timeout }
+}
+
+// WithMaxConnLifetime sets Options.MaxConnLifetime and MaxConnLifetimeJitter.
+func WithMaxConnLifetime(lifetime, jitter time.Duration) Option {
+	return func(o *Options) {
+		o.MaxConnLifetime = lifetime
+		o.MaxConnLifetimeJitter = jitter
+	}
+}
+
+// WithLeakDetection sets Options.LeakThreshold and OnLeak.
+func WithLeakDetection(threshold time.Duration, onLeak func(Checkout)) Option {
+	return func(o *Options) {
+		o.LeakThreshold = threshold
+		o.OnLeak = onLeak
+	}
+}
+
+// WithBudget sets Options.Budget.
+func WithBudget(budget *Budget) Option {
+	return func(o *Options) { o.Budget = budget }
+}
+
+// WithLogger sets Options.Logger.
+func WithLogger(logger Logger) Option {
+	return func(o *Options) { o.Logger = logger }
+}
+
+// WithObserver sets Options.Observer.
+func WithObserver(observer Observer) Option {
+	return func(o *Options) { o.Observer = observer }
+}
+
+// WithScalingPolicy sets Options.ScalingPolicy and ShrinkInterval.
+func WithScalingPolicy(policy ScalingPolicy, interval time.Duration) Option {
+	return func(o *Options) {
+		o.ScalingPolicy = policy
+		o.ShrinkInterval = interval
+	}
+}
+
+// WithBalancer sets Options.Balancer.
+func WithBalancer(balancer Balancer) Option {
+	return func(o *Options) { o.Balancer = balancer }
+}
+
+// WithRemoveTimeout sets Options.RemoveTimeout.
+func WithRemoveTimeout(timeout time.Duration) Option {
+	return func(o *Options) { o.RemoveTimeout = timeout }
+}
+
+// Dial return a grpc connection with defined configurations of DefaultDialConfig.
 func Dial(address string) (*grpc.ClientConn, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DialTimeout)
-	defer cancel()
-	return grpc.DialContext(ctx, address, grpc.WithInsecure(),
-		grpc.WithBackoffMaxDelay(BackoffMaxDelay),
-		grpc.WithInitialWindowSize(InitialWindowSize),
-		grpc.WithInitialConnWindowSize(InitialConnWindowSize),
-		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(MaxSendMsgSize)),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(MaxRecvMsgSize)),
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                KeepAliveTime,
-			Timeout:             KeepAliveTimeout,
-			PermitWithoutStream: true,
-		}))
+	return DefaultDialConfig.Dial(address)
 }
 
 // DialTest return a simple grpc connection with defined configurations.
@@ -0,0 +1,160 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelpool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shimingyah/pool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const namespace = "grpc.pool."
+
+// PoolKey is the attribute distinguishing the pools of Metrics.
+const PoolKey = attribute.Key("pool")
+
+// Metrics exposes the statistics of one or many pools as OpenTelemetry
+// asynchronous instruments, each pool is distinguished by the "pool"
+// attribute. The instruments are read from Stats() of the pools when
+// they are collected. The distribution of the Get latency is recorded by
+// NewPool with WithMeterProvider.
+type Metrics struct {
+	mu    sync.RWMutex
+	pools map[string]pool.Pool
+
+	registration metric.Registration
+
+	conns        metric.Int64ObservableGauge
+	activeConns  metric.Int64ObservableGauge
+	idleConns    metric.Int64ObservableGauge
+	ref          metric.Int64ObservableGauge
+	waiters      metric.Int64ObservableGauge
+	waits        metric.Int64ObservableCounter
+	grows        metric.Int64ObservableCounter
+	shrinks      metric.Int64ObservableCounter
+	dialFailures metric.Int64ObservableCounter
+	budgetDenied metric.Int64ObservableCounter
+	oneTimeConns metric.Int64ObservableCounter
+	oneTimeClose metric.Int64ObservableCounter
+	gets         metric.Int64ObservableCounter
+	getDuration  metric.Float64ObservableCounter
+}
+
+// NewMetrics return the metrics registered to the meter provider, the
+// global one is used if mp is nil.
+func NewMetrics(mp metric.MeterProvider) (*Metrics, error) {
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName)
+	m := &Metrics{pools: make(map[string]pool.Pool)}
+
+	var err error
+	gauge := func(name, desc string) metric.Int64ObservableGauge {
+		if err != nil {
+			return nil
+		}
+		var g metric.Int64ObservableGauge
+		g, err = meter.Int64ObservableGauge(namespace+name, metric.WithDescription(desc))
+		return g
+	}
+	counter := func(name, desc string) metric.Int64ObservableCounter {
+		if err != nil {
+			return nil
+		}
+		var c metric.Int64ObservableCounter
+		c, err = meter.Int64ObservableCounter(namespace+name, metric.WithDescription(desc))
+		return c
+	}
+
+	m.conns = gauge("connections", "The number of physical connections.")
+	m.activeConns = gauge("active_connections", "The number of physical connections with logical connections in use.")
+	m.idleConns = gauge("idle_connections", "The number of physical connections without logical connections in use.")
+	m.ref = gauge("refs", "The number of logical connections in use.")
+	m.waiters = gauge("waiters", "The number of callers waiting for a logical connection.")
+	m.waits = counter("waits", "The total number of Get calls waited for a logical connection.")
+	m.grows = counter("grows", "The total number of pool growths.")
+	m.shrinks = counter("shrinks", "The total number of pool shrinks.")
+	m.dialFailures = counter("dial_failures", "The total number of failed dials.")
+	m.budgetDenied = counter("budget_denials", "The total number of dials denied by the budget.")
+	m.oneTimeConns = counter("one_time_connections", "The total number of one-time connections created.")
+	m.oneTimeClose = counter("one_time_connections_closed", "The total number of one-time connections closed.")
+	m.gets = counter("gets", "The total number of Get calls.")
+	if err != nil {
+		return nil, err
+	}
+	m.getDuration, err = meter.Float64ObservableCounter(namespace+"get_duration",
+		metric.WithDescription("The total time spent in Get calls."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	m.registration, err = meter.RegisterCallback(m.observe,
+		m.conns, m.activeConns, m.idleConns, m.ref, m.waiters,
+		m.waits, m.grows, m.shrinks, m.dialFailures, m.budgetDenied,
+		m.oneTimeConns, m.oneTimeClose, m.gets, m.getDuration)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Add adds the pool with the name to the metrics.
+func (m *Metrics) Add(name string, p pool.Pool) {
+	m.mu.Lock()
+	m.pools[name] = p
+	m.mu.Unlock()
+}
+
+// Remove removes the pool with the name from the metrics.
+func (m *Metrics) Remove(name string) {
+	m.mu.Lock()
+	delete(m.pools, name)
+	m.mu.Unlock()
+}
+
+// Close unregisters the metrics from the meter.
+func (m *Metrics) Close() error {
+	return m.registration.Unregister()
+}
+
+func (m *Metrics) observe(_ context.Context, o metric.Observer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name, p := range m.pools {
+		s := p.Stats()
+		attrs := metric.WithAttributes(PoolKey.String(name), AddressKey.String(s.Address))
+		o.ObserveInt64(m.conns, int64(s.Current), attrs)
+		o.ObserveInt64(m.activeConns, int64(s.Active), attrs)
+		o.ObserveInt64(m.idleConns, int64(s.Idle), attrs)
+		o.ObserveInt64(m.ref, int64(s.Ref), attrs)
+		o.ObserveInt64(m.waiters, int64(s.Waiters), attrs)
+		o.ObserveInt64(m.waits, int64(s.Waits), attrs)
+		o.ObserveInt64(m.grows, int64(s.Grows), attrs)
+		o.ObserveInt64(m.shrinks, int64(s.Shrinks), attrs)
+		o.ObserveInt64(m.dialFailures, int64(s.DialFailures), attrs)
+		o.ObserveInt64(m.budgetDenied, int64(s.BudgetDenials), attrs)
+		o.ObserveInt64(m.oneTimeConns, int64(s.OneTimeCreated), attrs)
+		o.ObserveInt64(m.oneTimeClose, int64(s.OneTimeClosed), attrs)
+		o.ObserveInt64(m.gets, int64(s.Gets), attrs)
+		o.ObserveFloat64(m.getDuration, s.GetLatency.Seconds(), attrs)
+	}
+	return nil
+}
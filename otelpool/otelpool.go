@@ -0,0 +1,215 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelpool instruments pools with OpenTelemetry tracing and metrics.
+package otelpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shimingyah/pool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const instrumentationName = "github.com/shimingyah/pool/otelpool"
+
+// The attributes of the spans.
+const (
+	AddressKey   = attribute.Key("pool.address")
+	WaitKey      = attribute.Key("pool.wait_seconds")
+	ConnIndexKey = attribute.Key("pool.conn.index")
+	GrewKey      = attribute.Key("pool.grew")
+	OneTimeKey   = attribute.Key("pool.one_time")
+)
+
+// tracedPool traces each GetContext with a "pool.Get" span, and each
+// returned Conn with a "pool.Checkout" span ended by Conn.Close.
+type tracedPool struct {
+	pool.Pool
+	address string
+	tracer  trace.Tracer
+
+	// getLatency records the duration of each GetContext, nil if disabled.
+	getLatency metric.Float64Histogram
+}
+
+// Option configures the pool returned by NewPool.
+type Option func(*tracedPool)
+
+// WithMeterProvider records the duration of each GetContext, including
+// the failed ones, as the "grpc.pool.get_latency" histogram of the meter
+// provider. Metrics only exposes the total time spent in Get.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(t *tracedPool) {
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+		h, err := mp.Meter(instrumentationName).Float64Histogram(namespace+"get_latency",
+			metric.WithDescription("The duration of Get calls."), metric.WithUnit("s"))
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		t.getLatency = h
+	}
+}
+
+// NewPool return a pool traced by the tracer provider, the global one is
+// used if tp is nil. The spans are children of the span in the ctx of
+// GetContext, Invoke and NewStream.
+func NewPool(p pool.Pool, tp trace.TracerProvider, opts ...Option) pool.Pool {
+	t := &tracedPool{
+		Pool:    p,
+		address: p.Stats().Address,
+		tracer:  tracer(tp),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// Get see pool.Pool interface.
+func (t *tracedPool) Get() (pool.Conn, error) {
+	return t.GetContext(context.Background())
+}
+
+// GetContext see pool.Pool interface.
+func (t *tracedPool) GetContext(ctx context.Context) (pool.Conn, error) {
+	getCtx, span := t.tracer.Start(ctx, "pool.Get",
+		trace.WithAttributes(AddressKey.String(t.address)))
+	defer span.End()
+
+	var info pool.GetInfo
+	start := time.Now()
+	conn, err := t.Pool.GetContext(pool.WithGetInfo(getCtx, &info))
+	if t.getLatency != nil {
+		t.getLatency.Record(ctx, time.Since(start).Seconds(),
+			metric.WithAttributes(AddressKey.String(t.address)))
+	}
+	span.SetAttributes(WaitKey.Float64(info.Waited.Seconds()), GrewKey.Bool(info.Grew))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(ConnIndexKey.Int(info.Index), OneTimeKey.Bool(info.OneTime))
+
+	_, checkout := t.tracer.Start(ctx, "pool.Checkout",
+		trace.WithAttributes(AddressKey.String(t.address),
+			ConnIndexKey.Int(info.Index), OneTimeKey.Bool(info.OneTime)))
+	return &tracedConn{Conn: conn, span: checkout}, nil
+}
+
+// Invoke see grpc.ClientConnInterface, the RPC span is a child of the
+// checkout span.
+func (t *tracedPool) Invoke(ctx context.Context, method string, args, reply interface{},
+	opts ...grpc.CallOption) error {
+	conn, err := t.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Value().Invoke(Context(ctx, conn), method, args, reply, opts...)
+}
+
+// NewStream see grpc.ClientConnInterface, the RPC span is a child of the
+// checkout span.
+func (t *tracedPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, err := t.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, grpc.OnFinish(func(error) { conn.Close() }))
+	cs, err := conn.Value().NewStream(Context(ctx, conn), desc, method, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
+// tracedConn ends the checkout span when it's closed.
+type tracedConn struct {
+	pool.Conn
+	span trace.Span
+	once sync.Once
+}
+
+// Close see pool.Conn interface.
+func (c *tracedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.span.End()
+	})
+	return err
+}
+
+// Context return the ctx with the checkout span of the conn returned by
+// the traced pool, so that the spans of the RPCs on the conn are its
+// children. The ctx is returned as is for the other conns.
+func Context(ctx context.Context, conn pool.Conn) context.Context {
+	c, ok := conn.(*tracedConn)
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, c.span)
+}
+
+// Observer is a pool.Observer which records a "pool.Dial" span for each
+// dial, including the dials of growth in background, and forwards all of
+// the events to the next observer.
+type Observer struct {
+	pool.Observer
+	tracer trace.Tracer
+}
+
+// NewObserver return an observer traced by the tracer provider, the global
+// one is used if tp is nil. The events are forwarded to next if it's not nil.
+func NewObserver(tp trace.TracerProvider, next pool.Observer) *Observer {
+	if next == nil {
+		next = pool.NopObserver{}
+	}
+	return &Observer{Observer: next, tracer: tracer(tp)}
+}
+
+// OnDial see pool.Observer interface.
+func (o *Observer) OnDial(address string, err error, latency time.Duration) {
+	end := time.Now()
+	_, span := o.tracer.Start(context.Background(), "pool.Dial",
+		trace.WithTimestamp(end.Add(-latency)),
+		trace.WithAttributes(AddressKey.String(address)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+	o.Observer.OnDial(address, err, latency)
+}
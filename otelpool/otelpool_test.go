@@ -0,0 +1,188 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shimingyah/pool"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const address = "127.0.0.1:50000"
+
+func newPool(t *testing.T, opt pool.Options) pool.Pool {
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	p, err := pool.New(address, opt)
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestPoolTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	opt := pool.DefaultOptions
+	opt.Dial = pool.DialTest
+	p := NewPool(newPool(t, opt), tp)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "rpc")
+	conn1, err := p.GetContext(ctx)
+	require.NoError(t, err)
+	checkout := trace.SpanFromContext(Context(ctx, conn1)).(sdktrace.ReadOnlySpan)
+	require.Equal(t, "pool.Checkout", checkout.Name())
+	require.Equal(t, parent.SpanContext(), checkout.Parent())
+
+	conn2, err := p.GetContext(ctx)
+	require.NoError(t, err)
+	require.NoError(t, conn1.Close())
+	require.Equal(t, pool.ErrConnClosed, conn1.Close())
+	require.NoError(t, conn2.Close())
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 5)
+	require.Equal(t, "pool.Get", spans[0].Name())
+	require.Equal(t, parent.SpanContext(), spans[0].Parent())
+	a := attrs(spans[0])
+	require.Equal(t, address, a[AddressKey].AsString())
+	require.EqualValues(t, 0, a[ConnIndexKey].AsInt64())
+	require.False(t, a[GrewKey].AsBool())
+	require.False(t, a[OneTimeKey].AsBool())
+
+	// the second Get starts growing the pool.
+	require.Equal(t, "pool.Get", spans[1].Name())
+	require.True(t, attrs(spans[1])[GrewKey].AsBool())
+
+	require.Equal(t, "pool.Checkout", spans[2].Name())
+	require.Equal(t, "pool.Checkout", spans[3].Name())
+	require.Equal(t, "rpc", spans[4].Name())
+}
+
+func TestObserverTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	errDial := errors.New("dial failed")
+	opt := pool.DefaultOptions
+	opt.Dial = func(address string) (*grpc.ClientConn, error) {
+		return nil, errDial
+	}
+	opt.MaxIdle = 1
+	opt.Observer = NewObserver(tp, nil)
+	_, err := pool.New(address, opt)
+	require.Error(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "pool.Dial", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, errDial.Error(), spans[0].Status().Description)
+}
+
+func TestPoolGetLatency(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	opt := pool.DefaultOptions
+	opt.Dial = pool.DialTest
+	p := NewPool(newPool(t, opt), nil, WithMeterProvider(mp))
+
+	for i := 0; i < 3; i++ {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	md := rm.ScopeMetrics[0].Metrics[0]
+	require.Equal(t, "grpc.pool.get_latency", md.Name)
+	require.Equal(t, "s", md.Unit)
+	data, ok := md.Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, data.DataPoints, 1)
+	require.EqualValues(t, 3, data.DataPoints[0].Count)
+	v, ok := data.DataPoints[0].Attributes.Value(AddressKey)
+	require.True(t, ok)
+	require.Equal(t, address, v.AsString())
+}
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	opt := pool.DefaultOptions
+	opt.Dial = pool.DialTest
+	p := newPool(t, opt)
+
+	m, err := NewMetrics(mp)
+	require.NoError(t, err)
+	defer m.Close()
+	m.Add("echo", p)
+
+	conn, err := p.Get()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	values := make(map[string]int64)
+	for _, md := range rm.ScopeMetrics[0].Metrics {
+		switch data := md.Data.(type) {
+		case metricdata.Gauge[int64]:
+			require.Len(t, data.DataPoints, 1)
+			values[md.Name] = data.DataPoints[0].Value
+		case metricdata.Sum[int64]:
+			require.Len(t, data.DataPoints, 1)
+			values[md.Name] = data.DataPoints[0].Value
+		}
+	}
+	require.EqualValues(t, 1, values["grpc.pool.connections"])
+	require.EqualValues(t, 1, values["grpc.pool.refs"])
+	require.EqualValues(t, 1, values["grpc.pool.gets"])
+	require.EqualValues(t, 0, values["grpc.pool.dial_failures"])
+
+	m.Remove("echo")
+	rm = metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			if data, ok := md.Data.(metricdata.Gauge[int64]); ok {
+				require.Empty(t, data.DataPoints)
+			}
+		}
+	}
+}
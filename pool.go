@@ -15,12 +15,15 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
 // ErrClosed is the error resulting if the pool is closed via pool.Close().
@@ -34,16 +37,50 @@ type Pool interface {
 	// be counted as an error. we guarantee the conn.Value() isn't nil when conn isn't nil.
 	Get() (Conn, error)
 
-	// Close closes the pool and all its connections. After Close() the pool is
-	// no longer usable. You can't make concurrent calls Close and Get method.
-	// It will be cause panic.
+	// GetContext is like Get, but if the pool is saturated and Options.Wait is
+	// true, it blocks until a logical connection is given back to the pool,
+	// the pool is closed or the ctx is done.
+	GetContext(ctx context.Context) (Conn, error)
+
+	// Close closes the pool and all its connections immediately, the in-flight
+	// RPCs are canceled. After Close() the pool is no longer usable.
 	Close() error
 
+	// WaitReady blocks until the pool has Options.MinReady connections,
+	// or ctx is done, or the pool is closed.
+	WaitReady(ctx context.Context) error
+
+	// Shutdown stops the pool from returning new connections, waits for all of
+	// the returned connections to be closed or the ctx is done, then closes
+	// all its connections. It returns the ctx error if the ctx is done first.
+	// Close and Shutdown are safe to call concurrently with Get and Conn.Close.
+	Shutdown(ctx context.Context) error
+
 	// Status returns the current status of the pool.
 	Status() string
+
+	// Stats returns the statistics of the pool.
+	Stats() PoolStats
+
+	// Resize changes the limits of the live pool. Raising them grows the
+	// capacity, lowering them retires the surplus connections gracefully,
+	// the logical connections in use are kept until closed.
+	Resize(maxIdle, maxActive, maxConcurrentStreams int) error
+
+	// Checkouts returns the outstanding Conns ordered by the time of Get,
+	// it's empty unless leak detection is enabled by Options.LeakThreshold.
+	Checkouts() []Checkout
+
+	// Pool can be used as the connection of generated grpc clients directly.
+	// Each unary RPC acquires a logical connection and gives it back when it's
+	// done, each stream holds the logical connection until it's finished.
+	grpc.ClientConnInterface
 }
 
 type pool struct {
+	// atomic, the statistics counters of pool
+	counters counters
+
 	// atomic, used to get connection random
 	index uint32
 
@@ -54,8 +91,9 @@ type pool struct {
 	// logic connection = physical connection * MaxConcurrentStreams
 	ref int32
 
-	// pool options
-	opt Options
+	// pool options, the limits are changed by Resize.
+	opt    Options
+	limits limits
 
 	// all of created physical connections
 	conns []*conn
@@ -66,44 +104,127 @@ type pool struct {
 	// closed set true when Close is called.
 	closed int32
 
+	// the callers blocked in GetContext when the pool is saturated.
+	waiters waitQueue
+
+	// done is closed when Close is called to stop the background goroutines.
+	done chan struct{}
+
+	// the outstanding handles for leak detection.
+	tracker tracker
+
+	// atomic, set to 1 while the pool is growing in background.
+	growing int32
+
+	// the backoff of growth after failed dials.
+	backoff backoff
+
+	// fill the idle connections once for InitAsync and InitLazy, filled
+	// is broadcasted when connections are added or the dial is failed.
+	fillOnce sync.Once
+	filled   signal
+
+	// drained is broadcasted when the ref is down to zero, Shutdown waits
+	// for it.
+	drained signal
+
+	// report OnClose once for the repeated Close.
+	closeOnce sync.Once
+
+	// the recent events shown by DebugHandler.
+	events eventLog
+
+	// the credentials of DialConfig.TLSFiles, nil if not set.
+	certs *certWatcher
+
 	// control the atomic var current's concurrent read write.
 	sync.RWMutex
 }
 
 // New return a connection pool.
 func New(address string, option Options) (Pool, error) {
+	return NewContext(context.Background(), address, option)
+}
+
+// NewContext return a connection pool, the ctx bounds the dials of InitSync.
+// With InitAsync and InitLazy, the connections are dialed in background.
+func NewContext(ctx context.Context, address string, option Options) (Pool, error) {
 	if address == "" {
 		return nil, errors.New("invalid address settings")
 	}
-	if option.Dial == nil {
-		return nil, errors.New("invalid dial settings")
-	}
-	if option.MaxIdle <= 0 || option.MaxActive <= 0 || option.MaxIdle > option.MaxActive {
-		return nil, errors.New("invalid maximum settings")
-	}
-	if option.MaxConcurrentStreams <= 0 {
-		return nil, errors.New("invalid maximun settings")
+	if err := option.Validate(); err != nil {
+		return nil, err
 	}
 
 	p := &pool{
 		index:   0,
-		current: int32(option.MaxIdle),
+		current: 0,
 		ref:     0,
 		opt:     option,
 		conns:   make([]*conn, option.MaxActive),
 		address: address,
 		closed:  0,
+		done:    make(chan struct{}),
+		limits: limits{
+			maxIdle:    int32(option.MaxIdle),
+			maxActive:  int32(option.MaxActive),
+			maxStreams: int32(option.MaxConcurrentStreams),
+		},
+	}
+	if p.opt.DialConfig != nil {
+		config := *p.opt.DialConfig
+		if config.Credentials == nil && config.TLSFiles != nil {
+			certs, err := newCertWatcher(*config.TLSFiles)
+			if err != nil {
+				return nil, err
+			}
+			config.Credentials = certs
+			p.certs = certs
+		}
+		p.opt.Dial = config.Dial
+	}
+	if p.opt.Logger == nil {
+		p.opt.Logger = defaultLogger
+	}
+	if p.opt.Observer == nil {
+		p.opt.Observer = NopObserver{}
 	}
 
-	for i := 0; i < p.opt.MaxIdle; i++ {
-		c, err := p.opt.Dial(address)
-		if err != nil {
+	if p.opt.Budget != nil {
+		if granted := p.acquireBudget(p.opt.MaxIdle); granted < p.opt.MaxIdle {
+			p.releaseBudget(granted)
+			return nil, ErrBudgetExhausted
+		}
+		p.opt.Budget.register(p)
+	}
+
+	switch p.opt.InitMode {
+	case InitAsync:
+		p.startFill(p.opt.MaxIdle)
+	case InitLazy:
+	default:
+		if err := p.initSync(ctx); err != nil {
 			p.Close()
-			return nil, fmt.Errorf("dial is not able to fill the pool: %s", err)
+			return nil, err
 		}
-		p.conns[i] = p.wrapConn(c, false)
 	}
-	log.Printf("new pool success: %v\n", p.Status())
+	p.opt.Logger.Info("new pool success", "status", p.Status())
+
+	if p.opt.HealthCheckInterval > 0 {
+		go p.healthCheck()
+	}
+	if p.opt.IdleTimeout > 0 || p.opt.MaxConnLifetime > 0 {
+		go p.janitor()
+	}
+	if p.opt.LeakThreshold > 0 {
+		go p.leakCheck()
+	}
+	if p.opt.ScalingPolicy != nil {
+		go p.scaler()
+	}
+	if p.certs != nil {
+		go p.watchCerts()
+	}
 
 	return p, nil
 }
@@ -121,15 +242,39 @@ func (p *pool) decrRef() {
 	if newRef < 0 && atomic.LoadInt32(&p.closed) == 0 {
 		panic(fmt.Sprintf("negative ref: %d", newRef))
 	}
-	if newRef == 0 && atomic.LoadInt32(&p.current) > int32(p.opt.MaxIdle) {
-		p.Lock()
-		if atomic.LoadInt32(&p.ref) == 0 {
-			log.Printf("shrink pool: %d ---> %d, decrement: %d, maxActive: %d\n",
-				p.current, p.opt.MaxIdle, p.current-int32(p.opt.MaxIdle), p.opt.MaxActive)
-			atomic.StoreInt32(&p.current, int32(p.opt.MaxIdle))
-			p.deleteFrom(p.opt.MaxIdle)
-		}
-		p.Unlock()
+	if p.waiters.len() > 0 {
+		p.notify()
+	}
+	if newRef == 0 {
+		p.drained.broadcast()
+		p.shrink()
+	}
+}
+
+// shrink closes the connections beyond MaxIdle if none of them is in use,
+// or retires the ones decided by Options.ScalingPolicy.
+func (p *pool) shrink() {
+	if atomic.LoadInt32(&p.current) <= p.maxIdle() {
+		return
+	}
+	if p.opt.ScalingPolicy != nil {
+		p.scale()
+		return
+	}
+	p.Lock()
+	maxIdle := p.maxIdle()
+	current := atomic.LoadInt32(&p.current)
+	shrunk := atomic.LoadInt32(&p.ref) == 0 && current > maxIdle
+	if shrunk {
+		p.opt.Logger.Info("shrink pool", "from", current, "to", maxIdle,
+			"decrement", current-maxIdle, "maxActive", p.maxActive())
+		atomic.StoreInt32(&p.current, maxIdle)
+		p.deleteFrom(int(maxIdle))
+		atomic.AddUint64(&p.counters.shrinks, 1)
+	}
+	p.Unlock()
+	if shrunk {
+		p.shrank(int(current), int(maxIdle))
 	}
 }
 
@@ -143,85 +288,252 @@ func (p *pool) reset(index int) {
 }
 
 func (p *pool) deleteFrom(begin int) {
-	for i := begin; i < p.opt.MaxActive; i++ {
+	var n int
+	for i := begin; i < len(p.conns); i++ {
+		if p.conns[i] != nil {
+			n++
+		}
 		p.reset(i)
 	}
+	p.releaseBudget(n)
 }
 
 // Get see Pool interface.
 func (p *pool) Get() (Conn, error) {
+	return p.GetContext(context.Background())
+}
+
+// GetContext see Pool interface.
+func (p *pool) GetContext(ctx context.Context) (Conn, error) {
+	start := time.Now()
+	conn, err := p.get(ctx)
+	p.counters.observeGet(time.Since(start))
+	return conn, err
+}
+
+func (p *pool) get(ctx context.Context) (Conn, error) {
+	info := getInfoFrom(ctx)
+	var nextRef int32
+	if p.opt.Wait {
+		start := time.Now()
+		ref, err := p.wait(ctx)
+		info.waited(time.Since(start))
+		if err != nil {
+			return nil, err
+		}
+		nextRef = ref
+	} else {
+		nextRef = p.incrRef()
+	}
+	// the closed must be checked after the ref is increased,
+	// Shutdown waits for the ref to be zero after the pool is closed.
+	if atomic.LoadInt32(&p.closed) == 1 {
+		p.decrRef()
+		return nil, ErrClosed
+	}
+
 	// the first selected from the created connections
-	nextRef := p.incrRef()
 	p.RLock()
 	current := atomic.LoadInt32(&p.current)
 	p.RUnlock()
 	if current == 0 {
-		return nil, ErrClosed
+		// the connections are not dialed yet with InitAsync or InitLazy.
+		start := time.Now()
+		err := p.waitConn(ctx)
+		info.waited(time.Since(start))
+		if err != nil {
+			p.decrRef()
+			return nil, err
+		}
+		current = atomic.LoadInt32(&p.current)
 	}
-	if nextRef <= current*int32(p.opt.MaxConcurrentStreams) {
-		next := atomic.AddUint32(&p.index, 1) % uint32(current)
-		return p.conns[next], nil
+	if nextRef <= current*p.maxStreams() {
+		return p.next(info)
 	}
 
 	// the number connection of pool is reach to max active
-	if current == int32(p.opt.MaxActive) {
-		// the second if reuse is true, select from pool's connections
-		if p.opt.Reuse {
-			next := atomic.AddUint32(&p.index, 1) % uint32(current)
-			return p.conns[next], nil
+	if current >= p.maxActive() {
+		// the second if reuse is true, select from pool's connections,
+		// or if the dial is backing off or the budget is exhausted.
+		if p.opt.Reuse || !p.backoff.allow() || p.acquireBudget(1) == 0 {
+			return p.next(info)
+		}
+		// the third create one-time connection, fall back to the pool's
+		// connections if the dial is failed.
+		c, err := p.dialConn()
+		if err != nil {
+			p.releaseBudget(1)
+			p.dialFailed(err)
+			p.growFailed(err)
+			return p.next(info)
+		}
+		p.backoff.reset()
+		atomic.AddUint64(&p.counters.oneTimeCreated, 1)
+		p.opt.Observer.OnOneTimeConn(p.address)
+		conn := p.wrapConn(c, true)
+		conn.inflight = 1
+		conn.uses = 1
+		if info != nil {
+			info.Index = -1
+			info.OneTime = true
 		}
-		// the third create one-time connection
-		c, err := p.opt.Dial(p.address)
-		return p.wrapConn(c, true), err
+		return conn.checkout(), nil
 	}
 
-	// the fourth create new connections given back to pool
-	p.Lock()
-	current = atomic.LoadInt32(&p.current)
-	if current < int32(p.opt.MaxActive) && nextRef > current*int32(p.opt.MaxConcurrentStreams) {
-		// 2 times the incremental or the remain incremental
-		increment := current
-		if current+increment > int32(p.opt.MaxActive) {
-			increment = int32(p.opt.MaxActive) - current
-		}
-		var i int32
-		var err error
-		for i = 0; i < increment; i++ {
-			c, er := p.opt.Dial(p.address)
-			if er != nil {
-				err = er
-				break
-			}
-			p.reset(int(current + i))
-			p.conns[current+i] = p.wrapConn(c, false)
+	// the fourth grow the pool in background, the request is served by
+	// the created connections meanwhile.
+	if p.grow(current) && info != nil {
+		info.Grew = true
+	}
+	return p.next(info)
+}
+
+// next return the next connection selected by Options.Selector, and
+// increase its in-flight count. The ref is decreased if the pool is closed.
+func (p *pool) next(info *GetInfo) (Conn, error) {
+	p.RLock()
+	defer p.RUnlock()
+	current := int(atomic.LoadInt32(&p.current))
+	if current == 0 {
+		p.decrRef()
+		return nil, ErrClosed
+	}
+	var index int
+	if p.opt.Selector == nil {
+		index = p.roundRobin(current)
+	} else {
+		index = p.selectConn(current)
+	}
+	if info != nil {
+		info.Index = index
+	}
+	c := p.conns[index]
+	atomic.AddInt32(&c.inflight, 1)
+	atomic.AddUint64(&c.uses, 1)
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+	return c.checkout(), nil
+}
+
+// roundRobin return the index of the next healthy connection by round-robin.
+// If none of the connections is healthy, return the next one anyway.
+func (p *pool) roundRobin(current int) int {
+	next := int(atomic.AddUint32(&p.index, 1) % uint32(current))
+	for i := 0; i < current; i++ {
+		index := (next + i) % current
+		if c := p.conns[index]; c != nil && c.healthy() {
+			return index
 		}
-		current += i
-		log.Printf("grow pool: %d ---> %d, increment: %d, maxActive: %d\n",
-			p.current, current, increment, p.opt.MaxActive)
-		atomic.StoreInt32(&p.current, current)
-		if err != nil {
-			p.Unlock()
-			return nil, err
+	}
+	return next
+}
+
+// selectConn return the index of the connection selected by Options.Selector
+// from the healthy connections. If none of the connections is healthy, select
+// from all of them.
+func (p *pool) selectConn(current int) int {
+	buf := getConnInfos(current)
+	defer putConnInfos(buf)
+
+	// the healthy ones are collected from the front, the unhealthy ones from
+	// the back, the connections are scanned once.
+	infos := *buf
+	healthy, unhealthy := 0, current
+	for i := 0; i < current; i++ {
+		c := p.conns[i]
+		if c == nil {
+			continue
+		}
+		info := ConnInfo{Index: i, InFlight: int(atomic.LoadInt32(&c.inflight))}
+		if c.healthy() {
+			infos[healthy] = info
+			healthy++
+		} else {
+			unhealthy--
+			infos[unhealthy] = info
 		}
 	}
-	p.Unlock()
-	next := atomic.AddUint32(&p.index, 1) % uint32(current)
-	return p.conns[next], nil
+	if healthy > 0 {
+		infos = infos[:healthy]
+	} else {
+		infos = infos[unhealthy:]
+	}
+	return infos[p.opt.Selector.Select(infos)].Index
 }
 
 // Close see Pool interface.
 func (p *pool) Close() error {
-	atomic.StoreInt32(&p.closed, 1)
+	p.stop()
+	p.teardown()
+	return nil
+}
+
+// Shutdown see Pool interface.
+func (p *pool) Shutdown(ctx context.Context) error {
+	p.stop()
+
+	var err error
+	for err == nil {
+		ch := p.drained.wait()
+		if atomic.LoadInt32(&p.ref) <= 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ch:
+		}
+	}
+
+	p.teardown()
+	return err
+}
+
+// stop marks the pool closed, stops the background goroutines and wakes up
+// the waiters, the returned connections are still usable.
+func (p *pool) stop() {
+	if atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+		close(p.done)
+	}
+	p.waiters.closeAll()
+}
+
+// teardown closes all of the connections of the closed pool.
+func (p *pool) teardown() {
+	p.Lock()
 	atomic.StoreUint32(&p.index, 0)
 	atomic.StoreInt32(&p.current, 0)
 	atomic.StoreInt32(&p.ref, 0)
-	p.deleteFrom(0)
-	log.Printf("close pool success: %v\n", p.Status())
-	return nil
+	var n int
+	for i, c := range p.conns {
+		if c != nil {
+			c.cc.Close()
+			p.conns[i] = nil
+			n++
+		}
+	}
+	p.Unlock()
+	if p.opt.Budget != nil {
+		p.opt.Budget.unregister(p)
+		p.releaseBudget(n)
+		// the budget reserved by New is released by fill, if it's never
+		// started with InitLazy, release it here and never start it.
+		if p.opt.InitMode == InitLazy {
+			p.fillOnce.Do(func() {
+				p.releaseBudget(p.opt.MaxIdle)
+			})
+		}
+	}
+	p.opt.Logger.Info("close pool success", "status", p.Status())
+	p.closeOnce.Do(func() {
+		p.opt.Observer.OnClose(p.address)
+	})
 }
 
 // Status see Pool interface.
 func (p *pool) Status() string {
-	return fmt.Sprintf("address:%s, index:%d, current:%d, ref:%d. option:%v",
-		p.address, p.index, p.current, p.ref, p.opt)
+	s := p.Stats()
+	return fmt.Sprintf("address:%s, current:%d, active:%d, idle:%d, ref:%d, waiters:%d, "+
+		"grows:%d, shrinks:%d, dial failures:%d, one-time:%d/%d",
+		s.Address, s.Current, s.Active, s.Idle, s.Ref, s.Waiters,
+		s.Grows, s.Shrinks, s.DialFailures, s.OneTimeClosed, s.OneTimeCreated)
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/shimingyah/pool/example/pb"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
 )
 
 var endpoint = flag.String("endpoint", "127.0.0.1:50000", "grpc server endpoint")
@@ -160,6 +161,7 @@ func TestBasicGet2(t *testing.T) {
 	require.NoError(t, err)
 	defer conn3.Close()
 
+	waitGrow(t, nativePool, 2)
 	require.EqualValues(t, 3, nativePool.index)
 	require.EqualValues(t, 3, nativePool.ref)
 	require.EqualValues(t, 2, nativePool.current)
@@ -173,7 +175,7 @@ func TestBasicGet2(t *testing.T) {
 	require.NoError(t, err)
 	defer conn5.Close()
 
-	nativeConn := conn5.(*conn)
+	nativeConn := conn5.(*handle).c
 	require.EqualValues(t, false, nativeConn.once)
 }
 
@@ -198,7 +200,7 @@ func TestBasicGet3(t *testing.T) {
 	require.NoError(t, err)
 	defer conn2.Close()
 
-	nativeConn := conn2.(*conn)
+	nativeConn := conn2.(*handle).c
 	require.EqualValues(t, true, nativeConn.once)
 }
 
@@ -231,6 +233,7 @@ func TestConcurrentGet(t *testing.T) {
 		}(i)
 	}
 	wg.Wait()
+	waitGrow(t, nativePool, int32(opt.MaxIdle))
 
 	require.EqualValues(t, 0, nativePool.ref)
 	require.EqualValues(t, opt.MaxIdle, nativePool.current)
@@ -238,6 +241,106 @@ func TestConcurrentGet(t *testing.T) {
 	require.EqualValues(t, true, nativePool.conns[opt.MaxIdle] == nil)
 }
 
+func TestDoubleClose(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	waitGrow(t, nativePool, 2)
+	require.True(t, conn1.(*handle).c == conn2.(*handle).c)
+
+	// the handles of the same physical connection are released separately.
+	require.NoError(t, conn1.Close())
+	require.Equal(t, ErrConnClosed, conn1.Close())
+	require.EqualValues(t, 1, nativePool.ref)
+	require.EqualValues(t, 2, nativePool.current)
+
+	// close after the pool shrank.
+	require.NoError(t, conn2.Close())
+	require.EqualValues(t, 1, nativePool.current)
+	require.Equal(t, ErrConnClosed, conn2.Close())
+	require.EqualValues(t, 0, nativePool.ref)
+}
+
+func TestShutdown(t *testing.T) {
+	p, nativePool, _, err := newPool(nil)
+	require.NoError(t, err)
+
+	conn, err := p.Get()
+	require.NoError(t, err)
+	cc := conn.Value()
+
+	done := make(chan error)
+	go func() {
+		done <- p.Shutdown(context.Background())
+	}()
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&nativePool.closed) == 1
+	})
+
+	_, err = p.Get()
+	require.Equal(t, ErrClosed, err)
+	require.NotEqual(t, connectivity.Shutdown, cc.GetState())
+
+	conn.Close()
+	require.NoError(t, <-done)
+	require.Equal(t, connectivity.Shutdown, cc.GetState())
+	require.EqualValues(t, 0, nativePool.current)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	p, nativePool, _, err := newPool(nil)
+	require.NoError(t, err)
+
+	conn, err := p.Get()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, p.Shutdown(ctx))
+	require.Equal(t, connectivity.Shutdown, conn.Value().GetState())
+	require.EqualValues(t, 0, nativePool.current)
+
+	conn.Close()
+}
+
+func TestConcurrentShutdown(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxConcurrentStreams = 2
+	p, _, _, err := newPool(&opt)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				conn, err := p.Get()
+				if err != nil {
+					require.Equal(t, ErrClosed, err)
+					return
+				}
+				conn.Close()
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, p.Shutdown(context.Background()))
+	wg.Wait()
+}
+
 var size = 4 * 1024 * 1024
 
 func BenchmarkPoolRPC(b *testing.B) {
@@ -274,6 +377,29 @@ func BenchmarkPoolRPC(b *testing.B) {
 	})
 }
 
+func BenchmarkPoolGetSelector(b *testing.B) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.Selector = NewLeastInFlightSelector()
+	p, err := New(*endpoint, opt)
+	if err != nil {
+		b.Fatalf("failed to new pool: %v", err)
+	}
+	defer p.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(tpb *testing.PB) {
+		for tpb.Next() {
+			conn, err := p.Get()
+			if err != nil {
+				b.Fatalf("failed to get conn: %v", err)
+			}
+			conn.Close()
+		}
+	})
+}
+
 func BenchmarkSingleRPC(b *testing.B) {
 	testFunc := func() {
 		cc, err := Dial(*endpoint)
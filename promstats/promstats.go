@@ -0,0 +1,140 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package promstats exposes the statistics of pools as prometheus metrics.
+package promstats
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shimingyah/pool"
+)
+
+const namespace = "grpc_pool"
+
+// Collector is a prometheus.Collector for one or many pools, each pool is
+// distinguished by the "pool" label. The metrics are read from Stats() of
+// the pools when they are collected.
+type Collector struct {
+	mu    sync.RWMutex
+	pools map[string]pool.Pool
+
+	conns        *prometheus.Desc
+	activeConns  *prometheus.Desc
+	idleConns    *prometheus.Desc
+	ref          *prometheus.Desc
+	waiters      *prometheus.Desc
+	waits        *prometheus.Desc
+	grows        *prometheus.Desc
+	shrinks      *prometheus.Desc
+	dialFailures *prometheus.Desc
+	budgetDenied *prometheus.Desc
+	oneTimeConns *prometheus.Desc
+	oneTimeClose *prometheus.Desc
+	getDuration  *prometheus.Desc
+}
+
+// NewCollector return a collector without any pool.
+func NewCollector() *Collector {
+	labels := []string{"pool", "address"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+	}
+	return &Collector{
+		pools:        make(map[string]pool.Pool),
+		conns:        desc("connections", "The number of physical connections."),
+		activeConns:  desc("active_connections", "The number of physical connections with logical connections in use."),
+		idleConns:    desc("idle_connections", "The number of physical connections without logical connections in use."),
+		ref:          desc("refs", "The number of logical connections in use."),
+		waiters:      desc("waiters", "The number of callers waiting for a logical connection."),
+		waits:        desc("waits_total", "The total number of Get calls waited for a logical connection."),
+		grows:        desc("grows_total", "The total number of pool growths."),
+		shrinks:      desc("shrinks_total", "The total number of pool shrinks."),
+		dialFailures: desc("dial_failures_total", "The total number of failed dials."),
+		budgetDenied: desc("budget_denials_total", "The total number of dials denied by the budget."),
+		oneTimeConns: desc("one_time_connections_total", "The total number of one-time connections created."),
+		oneTimeClose: desc("one_time_connections_closed_total", "The total number of one-time connections closed."),
+		getDuration:  desc("get_duration_seconds", "The time spent in Get calls."),
+	}
+}
+
+// Add adds the pool to the collector with the name as "pool" label,
+// the pool with the same name is replaced.
+func (c *Collector) Add(name string, p pool.Pool) {
+	c.mu.Lock()
+	c.pools[name] = p
+	c.mu.Unlock()
+}
+
+// Remove removes the pool with the name from the collector.
+func (c *Collector) Remove(name string) {
+	c.mu.Lock()
+	delete(c.pools, name)
+	c.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.conns
+	ch <- c.activeConns
+	ch <- c.idleConns
+	ch <- c.ref
+	ch <- c.waiters
+	ch <- c.waits
+	ch <- c.grows
+	ch <- c.shrinks
+	ch <- c.dialFailures
+	ch <- c.budgetDenied
+	ch <- c.oneTimeConns
+	ch <- c.oneTimeClose
+	ch <- c.getDuration
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for name, p := range c.pools {
+		s := p.Stats()
+		gauge := func(desc *prometheus.Desc, v int) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), name, s.Address)
+		}
+		counter := func(desc *prometheus.Desc, v uint64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), name, s.Address)
+		}
+
+		gauge(c.conns, s.Current)
+		gauge(c.activeConns, s.Active)
+		gauge(c.idleConns, s.Idle)
+		gauge(c.ref, s.Ref)
+		gauge(c.waiters, s.Waiters)
+		counter(c.waits, s.Waits)
+		counter(c.grows, s.Grows)
+		counter(c.shrinks, s.Shrinks)
+		counter(c.dialFailures, s.DialFailures)
+		counter(c.budgetDenied, s.BudgetDenials)
+		counter(c.oneTimeConns, s.OneTimeCreated)
+		counter(c.oneTimeClose, s.OneTimeClosed)
+
+		h := s.GetLatencyHistogram
+		buckets := make(map[float64]uint64, len(h.Bounds))
+		for i, bound := range h.Bounds {
+			buckets[bound.Seconds()] = h.Counts[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(c.getDuration, s.Gets,
+			s.GetLatency.Seconds(), buckets, name, s.Address)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package promstats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shimingyah/pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	opt := pool.DefaultOptions
+	opt.Dial = pool.DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	p, err := pool.New("127.0.0.1:50000", opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	c := NewCollector()
+	c.Add("echo", p)
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	defer conn1.Close()
+	conn2, err := p.Get()
+	require.NoError(t, err)
+	defer conn2.Close()
+
+	// the pool grows in background.
+	for i := 0; i < 1000 && p.Stats().Current < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := `
+# HELP grpc_pool_connections The number of physical connections.
+# TYPE grpc_pool_connections gauge
+grpc_pool_connections{address="127.0.0.1:50000",pool="echo"} 2
+# HELP grpc_pool_grows_total The total number of pool growths.
+# TYPE grpc_pool_grows_total counter
+grpc_pool_grows_total{address="127.0.0.1:50000",pool="echo"} 1
+# HELP grpc_pool_refs The number of logical connections in use.
+# TYPE grpc_pool_refs gauge
+grpc_pool_refs{address="127.0.0.1:50000",pool="echo"} 2
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"grpc_pool_connections", "grpc_pool_grows_total", "grpc_pool_refs"))
+
+	count, err := testutil.GatherAndCount(reg, "grpc_pool_get_duration_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	c.Remove("echo")
+	count, err = testutil.GatherAndCount(reg)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+)
+
+// limits are the atomic copies of the Options limits which can be changed
+// by Resize on a live pool.
+type limits struct {
+	maxIdle    int32
+	maxActive  int32
+	maxStreams int32
+}
+
+func (p *pool) maxIdle() int32 {
+	return atomic.LoadInt32(&p.limits.maxIdle)
+}
+
+func (p *pool) maxActive() int32 {
+	return atomic.LoadInt32(&p.limits.maxActive)
+}
+
+func (p *pool) maxStreams() int32 {
+	return atomic.LoadInt32(&p.limits.maxStreams)
+}
+
+// checkLimits validates the limits passed to Resize.
+func checkLimits(opt Options, maxIdle, maxActive, maxConcurrentStreams int) error {
+	return validateLimits(maxIdle, maxActive, maxConcurrentStreams, opt.MinReady)
+}
+
+// Resize see Pool interface.
+func (p *pool) Resize(maxIdle, maxActive, maxConcurrentStreams int) error {
+	if err := checkLimits(p.opt, maxIdle, maxActive, maxConcurrentStreams); err != nil {
+		return err
+	}
+
+	p.Lock()
+	if atomic.LoadInt32(&p.closed) == 1 {
+		p.Unlock()
+		return ErrClosed
+	}
+	if maxActive > len(p.conns) {
+		conns := make([]*conn, maxActive)
+		copy(conns, p.conns)
+		p.conns = conns
+	}
+	// retire the connections beyond maxActive, the in-flight logical
+	// connections are kept until closed.
+	current := int(atomic.LoadInt32(&p.current))
+	retired := 0
+	for i := maxActive; i < current; i++ {
+		if c := p.conns[i]; c != nil {
+			c.retire()
+			p.conns[i] = nil
+			retired++
+		}
+	}
+	prev := current
+	if current > maxActive {
+		current = maxActive
+		atomic.StoreInt32(&p.current, int32(current))
+	}
+	if maxActive < len(p.conns) {
+		p.conns = append([]*conn(nil), p.conns[:maxActive]...)
+	}
+	from := p.limits
+	atomic.StoreInt32(&p.limits.maxIdle, int32(maxIdle))
+	atomic.StoreInt32(&p.limits.maxActive, int32(maxActive))
+	atomic.StoreInt32(&p.limits.maxStreams, int32(maxConcurrentStreams))
+	p.Unlock()
+
+	p.releaseBudget(retired)
+	if current < prev {
+		p.shrank(prev, current)
+	}
+	p.opt.Logger.Info("resize pool", "address", p.address,
+		"maxIdle", from.maxIdle, "toMaxIdle", maxIdle,
+		"maxActive", from.maxActive, "toMaxActive", maxActive,
+		"maxConcurrentStreams", from.maxStreams, "toMaxConcurrentStreams", maxConcurrentStreams)
+
+	// the surplus idle connections are closed once none of them is in use.
+	if atomic.LoadInt32(&p.ref) == 0 {
+		p.shrink()
+	}
+	if current < maxIdle {
+		go p.replenish()
+	}
+	if p.waiters.len() > 0 {
+		p.notify()
+	}
+	return nil
+}
+
+// replenish dials the connections missing from MaxIdle once.
+func (p *pool) replenish() {
+	missing := p.acquireBudget(int(p.maxIdle() - atomic.LoadInt32(&p.current)))
+	if missing <= 0 {
+		return
+	}
+	dialed, err := p.dial(missing)
+	p.releaseBudget(missing - len(dialed))
+	if err != nil {
+		p.opt.Logger.Warn("replenish pool dial failed", "address", p.address, "error", err)
+	}
+	from, to := p.publish(dialed)
+	p.releaseBudget(len(dialed) - int(to-from))
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestResizeInvalid(t *testing.T) {
+	p, _, _, err := newPool(nil)
+	require.NoError(t, err)
+
+	require.Error(t, p.Resize(0, 1, 1))
+	require.Error(t, p.Resize(2, 1, 1))
+	require.Error(t, p.Resize(1, 1, 0))
+
+	p.Close()
+	require.Equal(t, ErrClosed, p.Resize(1, 1, 1))
+}
+
+func TestResizeRaise(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 1
+	opt.MaxConcurrentStreams = 1
+	opt.Wait = true
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+	defer conn1.Close()
+
+	// the waiter is woken up by the raised capacity.
+	done := make(chan error, 1)
+	go func() {
+		conn, err := p.Get()
+		if err == nil {
+			defer conn.Close()
+		}
+		done <- err
+	}()
+	waitFor(t, func() bool { return nativePool.waiters.queued() == 1 })
+	require.NoError(t, p.Resize(2, 4, 1))
+	require.NoError(t, <-done)
+
+	// the missing idle connection is dialed in background.
+	waitFor(t, func() bool { return atomic.LoadInt32(&nativePool.current) >= 2 })
+	nativePool.RLock()
+	require.Len(t, nativePool.conns, 4)
+	nativePool.RUnlock()
+}
+
+func TestResizeLower(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 2
+	opt.MaxActive = 4
+	opt.MaxConcurrentStreams = 1
+	opt.Selector = NewLeastInFlightSelector()
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the logical connections are on each of the 4 connections.
+	conns := make([]Conn, 0, 5)
+	for i := 0; i < 5; i++ {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		conns = append(conns, conn)
+		if i == 2 {
+			waitGrow(t, nativePool, 4)
+		}
+	}
+
+	nativePool.RLock()
+	surplus := nativePool.conns[3]
+	nativePool.RUnlock()
+	require.NoError(t, p.Resize(1, 3, 1))
+	nativePool.RLock()
+	require.EqualValues(t, 3, nativePool.current)
+	require.Len(t, nativePool.conns, 3)
+	nativePool.RUnlock()
+
+	// the retired one is closed after its logical connections are closed.
+	require.NotEqual(t, connectivity.Shutdown, surplus.cc.GetState())
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	require.Equal(t, connectivity.Shutdown, surplus.cc.GetState())
+	require.EqualValues(t, 1, atomic.LoadInt32(&nativePool.current))
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ScalingState is the state of the pool passed to ScalingPolicy.
+type ScalingState struct {
+	// Current is the number of physical connections.
+	Current int
+
+	// MaxIdle, MaxActive and MaxConcurrentStreams are the limits of the pool.
+	MaxIdle              int
+	MaxActive            int
+	MaxConcurrentStreams int
+
+	// Ref is the number of logical connections in use.
+	Ref int
+
+	// Idle is the idle duration of each surplus connection beyond MaxIdle
+	// without logical connections in use, ordered from the longest.
+	Idle []time.Duration
+}
+
+// ScalingPolicy decides how many surplus connections beyond MaxIdle are
+// retired. It's evaluated when the ref drops to zero and every
+// Options.ShrinkInterval. Implementations must be safe for concurrent use.
+type ScalingPolicy interface {
+	// Shrink return the number of the idle surplus connections to retire,
+	// the longest idle ones are retired first.
+	Shrink(state ScalingState) int
+}
+
+type cooldownPolicy struct {
+	cooldown time.Duration
+	step     int
+}
+
+// NewCooldownPolicy return a policy retires the surplus connections idle for
+// longer than cooldown, at most step of them each time if step is positive.
+func NewCooldownPolicy(cooldown time.Duration, step int) ScalingPolicy {
+	return &cooldownPolicy{cooldown: cooldown, step: step}
+}
+
+func (c *cooldownPolicy) Shrink(state ScalingState) int {
+	n := 0
+	for _, idle := range state.Idle {
+		if idle >= c.cooldown {
+			n++
+		}
+	}
+	if c.step > 0 && n > c.step {
+		n = c.step
+	}
+	return n
+}
+
+// scaler evaluates Options.ScalingPolicy every ShrinkInterval until the pool is closed.
+func (p *pool) scaler() {
+	interval := p.opt.ShrinkInterval
+	if interval <= 0 {
+		interval = ShrinkInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.scale()
+		}
+	}
+}
+
+// scale retires the idle surplus connections decided by Options.ScalingPolicy.
+func (p *pool) scale() {
+	type candidate struct {
+		c    *conn
+		idle time.Duration
+	}
+
+	now := time.Now().UnixNano()
+	p.Lock()
+	current := int(atomic.LoadInt32(&p.current))
+	maxIdle := int(p.maxIdle())
+	var candidates []candidate
+	for i := maxIdle; i < current; i++ {
+		c := p.conns[i]
+		if c != nil && atomic.LoadInt32(&c.inflight) == 0 {
+			idle := time.Duration(now - atomic.LoadInt64(&c.lastUsed))
+			candidates = append(candidates, candidate{c, idle})
+		}
+	}
+	if len(candidates) == 0 {
+		p.Unlock()
+		return
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].idle > candidates[j].idle
+	})
+
+	state := ScalingState{
+		Current:              current,
+		MaxIdle:              maxIdle,
+		MaxActive:            int(p.maxActive()),
+		MaxConcurrentStreams: int(p.maxStreams()),
+		Ref:                  int(atomic.LoadInt32(&p.ref)),
+		Idle:                 make([]time.Duration, len(candidates)),
+	}
+	for i, cand := range candidates {
+		state.Idle[i] = cand.idle
+	}
+	n := p.opt.ScalingPolicy.Shrink(state)
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	if n <= 0 {
+		p.Unlock()
+		return
+	}
+
+	retired := make(map[*conn]bool, n)
+	for _, cand := range candidates[:n] {
+		retired[cand.c] = true
+	}
+	// compact the surplus slots, the order of the kept ones is unchanged.
+	to := maxIdle
+	for i := maxIdle; i < current; i++ {
+		c := p.conns[i]
+		if retired[c] {
+			c.retire()
+			continue
+		}
+		p.conns[to] = c
+		to++
+	}
+	for i := to; i < current; i++ {
+		p.conns[i] = nil
+	}
+	atomic.StoreInt32(&p.current, int32(to))
+	p.Unlock()
+
+	p.releaseBudget(n)
+	atomic.AddUint64(&p.counters.shrinks, 1)
+	p.opt.Logger.Info("shrink pool", "from", current, "to", to,
+		"decrement", n, "maxActive", state.MaxActive)
+	p.shrank(current, to)
+}
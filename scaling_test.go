@@ -0,0 +1,76 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestCooldownPolicy(t *testing.T) {
+	state := ScalingState{Idle: []time.Duration{3 * time.Second, 2 * time.Second, time.Second}}
+	require.Equal(t, 1, NewCooldownPolicy(1500*time.Millisecond, 1).Shrink(state))
+	require.Equal(t, 2, NewCooldownPolicy(1500*time.Millisecond, 0).Shrink(state))
+	require.Equal(t, 0, NewCooldownPolicy(time.Minute, 0).Shrink(state))
+}
+
+func TestScalingPolicy(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 4
+	opt.MaxConcurrentStreams = 1
+	opt.ScalingPolicy = NewCooldownPolicy(time.Minute, 1)
+	opt.ShrinkInterval = time.Hour
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conns := make([]Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := p.Get()
+		require.NoError(t, err)
+		conns = append(conns, conn)
+		waitGrow(t, nativePool, int32([]int{1, 2, 4}[i]))
+	}
+
+	// the pool isn't shrunk immediately when the ref drops to zero.
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	require.EqualValues(t, 4, atomic.LoadInt32(&nativePool.current))
+
+	// one connection is retired each time after the cool-down.
+	nativePool.RLock()
+	surplus := append([]*conn(nil), nativePool.conns[1:4]...)
+	for _, c := range surplus {
+		atomic.StoreInt64(&c.lastUsed, time.Now().Add(-time.Hour).UnixNano())
+	}
+	nativePool.RUnlock()
+	for i := 3; i > 0; i-- {
+		require.EqualValues(t, i+1, atomic.LoadInt32(&nativePool.current))
+		nativePool.scale()
+	}
+	nativePool.scale()
+	require.EqualValues(t, 1, atomic.LoadInt32(&nativePool.current))
+	require.EqualValues(t, 3, p.Stats().Shrinks)
+	for _, c := range surplus {
+		require.Equal(t, connectivity.Shutdown, c.cc.GetState())
+	}
+}
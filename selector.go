@@ -0,0 +1,124 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ConnInfo describes a physical connection of the pool.
+type ConnInfo struct {
+	// Index is the slot index of the connection in the pool.
+	Index int
+
+	// InFlight is the number of logical connections in use of the connection.
+	InFlight int
+}
+
+// Selector selects a physical connection of the pool for each Get.
+// Implementations must be safe for concurrent use.
+type Selector interface {
+	// Select return the index of the selected connection in conns,
+	// conns isn't empty. conns is reused by the pool after Select returns,
+	// it must not be retained.
+	Select(conns []ConnInfo) int
+}
+
+// connInfos reuses the ConnInfo buffers passed to Selector by each Get.
+var connInfos = sync.Pool{
+	New: func() interface{} { return new([]ConnInfo) },
+}
+
+// getConnInfos return a buffer of n ConnInfo from connInfos.
+func getConnInfos(n int) *[]ConnInfo {
+	buf := connInfos.Get().(*[]ConnInfo)
+	if cap(*buf) < n {
+		*buf = make([]ConnInfo, n)
+	}
+	*buf = (*buf)[:n]
+	return buf
+}
+
+func putConnInfos(buf *[]ConnInfo) {
+	connInfos.Put(buf)
+}
+
+type roundRobinSelector struct {
+	index uint32
+}
+
+// NewRoundRobinSelector return a selector selects the connections in turn.
+func NewRoundRobinSelector() Selector {
+	return &roundRobinSelector{}
+}
+
+func (s *roundRobinSelector) Select(conns []ConnInfo) int {
+	return int((atomic.AddUint32(&s.index, 1) - 1) % uint32(len(conns)))
+}
+
+type randomSelector struct{}
+
+// NewRandomSelector return a selector selects the connections randomly.
+func NewRandomSelector() Selector {
+	return randomSelector{}
+}
+
+func (randomSelector) Select(conns []ConnInfo) int {
+	return rand.Intn(len(conns))
+}
+
+type leastInFlightSelector struct{}
+
+// NewLeastInFlightSelector return a selector selects the connection with
+// the least in-flight logical connections.
+func NewLeastInFlightSelector() Selector {
+	return leastInFlightSelector{}
+}
+
+func (leastInFlightSelector) Select(conns []ConnInfo) int {
+	least := 0
+	for i := 1; i < len(conns); i++ {
+		if conns[i].InFlight < conns[least].InFlight {
+			least = i
+		}
+	}
+	return least
+}
+
+type p2cSelector struct{}
+
+// NewP2CSelector return a selector selects two connections randomly
+// and picks the one with less in-flight logical connections,
+// which is known as the power of two choices.
+func NewP2CSelector() Selector {
+	return p2cSelector{}
+}
+
+func (p2cSelector) Select(conns []ConnInfo) int {
+	if len(conns) == 1 {
+		return 0
+	}
+	a := rand.Intn(len(conns))
+	b := rand.Intn(len(conns) - 1)
+	if b >= a {
+		b++
+	}
+	if conns[b].InFlight < conns[a].InFlight {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectors(t *testing.T) {
+	conns := []ConnInfo{{Index: 0, InFlight: 3}, {Index: 1, InFlight: 1}, {Index: 2, InFlight: 2}}
+
+	rr := NewRoundRobinSelector()
+	for i := 0; i < 6; i++ {
+		require.Equal(t, i%len(conns), rr.Select(conns))
+	}
+
+	require.Equal(t, 1, NewLeastInFlightSelector().Select(conns))
+
+	p2c := NewP2CSelector()
+	random := NewRandomSelector()
+	for i := 0; i < 100; i++ {
+		// the busiest connection never wins two choices.
+		require.NotEqual(t, 0, p2c.Select(conns))
+		n := random.Select(conns)
+		require.True(t, n >= 0 && n < len(conns))
+	}
+	require.Equal(t, 0, p2c.Select(conns[:1]))
+}
+
+func TestLeastInFlightGet(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 3
+	opt.Selector = NewLeastInFlightSelector()
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	conns := make([]Conn, 3)
+	for i := range conns {
+		conns[i], err = p.Get()
+		require.NoError(t, err)
+		require.True(t, conns[i].(*handle).c == nativePool.conns[i])
+		require.EqualValues(t, 1, nativePool.conns[i].inflight)
+	}
+
+	conns[1].Close()
+	require.EqualValues(t, 0, nativePool.conns[1].inflight)
+	conn, err := p.Get()
+	require.NoError(t, err)
+	require.True(t, conn.(*handle).c == nativePool.conns[1])
+}
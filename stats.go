@@ -0,0 +1,263 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats is the statistics of a pool. For the pool created by NewCluster,
+// the statistics of all sub-pools are summed up.
+type PoolStats struct {
+	// Address is the server address of the pool.
+	Address string
+
+	// Current is the number of physical connections.
+	Current int
+
+	// Active is the number of physical connections with logical connections in use.
+	Active int
+
+	// Idle is the number of physical connections without logical connections in use.
+	Idle int
+
+	// Ref is the number of logical connections in use.
+	Ref int
+
+	// Waiters is the number of callers waiting for a logical connection.
+	Waiters int
+
+	// Waits is the total number of Get() calls waited for a logical connection.
+	Waits uint64
+
+	// OneTimeCreated is the total number of one-time connections created.
+	OneTimeCreated uint64
+
+	// OneTimeClosed is the total number of one-time connections closed.
+	OneTimeClosed uint64
+
+	// Grows is the total number of pool growths.
+	Grows uint64
+
+	// Shrinks is the total number of pool shrinks.
+	Shrinks uint64
+
+	// DialFailures is the total number of failed dials.
+	DialFailures uint64
+
+	// BudgetDenials is the total number of dials denied by Options.Budget.
+	BudgetDenials uint64
+
+	// LastDialError is the error of the last failed dial, empty if none.
+	LastDialError string
+
+	// LastDialErrorTime is the time of the last failed dial.
+	LastDialErrorTime time.Time
+
+	// GrowBackoff is the remaining delay before the next growth after failed dials.
+	GrowBackoff time.Duration
+
+	// Gets is the total number of Get() calls.
+	Gets uint64
+
+	// GetLatency is the total time spent in Get() calls.
+	GetLatency time.Duration
+
+	// GetLatencyHistogram is the histogram of time spent in Get() calls.
+	GetLatencyHistogram Histogram
+
+	// Conns is the breakdown of each physical connection.
+	Conns []ConnStats
+}
+
+// ConnStats is the statistics of a physical connection.
+type ConnStats struct {
+	// Index is the slot index of the connection in the pool.
+	Index int
+
+	// State is the connectivity state of the grpc connection.
+	State string
+
+	// Healthy is false if the connection is marked unhealthy by health checking.
+	Healthy bool
+
+	// InFlight is the number of logical connections in use of the connection.
+	InFlight int
+
+	// Age is the duration since the connection is created.
+	Age time.Duration
+
+	// Uses is the total number of times the connection returned by Get().
+	Uses uint64
+}
+
+// Histogram is a cumulative histogram of durations.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets in increasing order.
+	Bounds []time.Duration
+
+	// Counts[i] is the number of observations less than or equal to Bounds[i].
+	Counts []uint64
+}
+
+// latencyBounds are the upper bounds of the Get() latency histogram.
+var latencyBounds = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// add sums up the statistics of another pool.
+func (s *PoolStats) add(o PoolStats) {
+	if s.Address == "" {
+		s.Address = o.Address
+	} else {
+		s.Address += "," + o.Address
+	}
+	s.Current += o.Current
+	s.Active += o.Active
+	s.Idle += o.Idle
+	s.Ref += o.Ref
+	s.Waiters += o.Waiters
+	s.Waits += o.Waits
+	s.OneTimeCreated += o.OneTimeCreated
+	s.OneTimeClosed += o.OneTimeClosed
+	s.Grows += o.Grows
+	s.Shrinks += o.Shrinks
+	s.DialFailures += o.DialFailures
+	s.BudgetDenials += o.BudgetDenials
+	if o.LastDialErrorTime.After(s.LastDialErrorTime) {
+		s.LastDialError = o.LastDialError
+		s.LastDialErrorTime = o.LastDialErrorTime
+	}
+	if o.GrowBackoff > s.GrowBackoff {
+		s.GrowBackoff = o.GrowBackoff
+	}
+	s.Gets += o.Gets
+	s.GetLatency += o.GetLatency
+	if len(s.GetLatencyHistogram.Counts) == 0 {
+		s.GetLatencyHistogram.Bounds = o.GetLatencyHistogram.Bounds
+		s.GetLatencyHistogram.Counts = make([]uint64, len(o.GetLatencyHistogram.Counts))
+	}
+	for i, n := range o.GetLatencyHistogram.Counts {
+		s.GetLatencyHistogram.Counts[i] += n
+	}
+	s.Conns = append(s.Conns, o.Conns...)
+}
+
+// counters are the atomic counters of pool, the struct must be the
+// first field of pool to guarantee 64-bit alignment.
+type counters struct {
+	waits          uint64
+	oneTimeCreated uint64
+	oneTimeClosed  uint64
+	grows          uint64
+	shrinks        uint64
+	dialFailures   uint64
+	budgetDenials  uint64
+	gets           uint64
+	getLatency     int64
+
+	// the non-cumulative counts of latencyBounds buckets.
+	getBuckets [len(latencyBounds)]uint64
+}
+
+func (c *counters) observeGet(latency time.Duration) {
+	atomic.AddUint64(&c.gets, 1)
+	atomic.AddInt64(&c.getLatency, int64(latency))
+	for i, bound := range latencyBounds {
+		if latency <= bound {
+			atomic.AddUint64(&c.getBuckets[i], 1)
+			break
+		}
+	}
+}
+
+func (c *counters) getHistogram() Histogram {
+	h := Histogram{
+		Bounds: append([]time.Duration(nil), latencyBounds[:]...),
+		Counts: make([]uint64, len(latencyBounds)),
+	}
+	var count uint64
+	for i := range latencyBounds {
+		count += atomic.LoadUint64(&c.getBuckets[i])
+		h.Counts[i] = count
+	}
+	return h
+}
+
+// Stats see Pool interface.
+func (p *pool) Stats() PoolStats {
+	s := PoolStats{
+		Address:        p.address,
+		Ref:            int(atomic.LoadInt32(&p.ref)),
+		Waiters:        int(p.waiters.len()),
+		Waits:          atomic.LoadUint64(&p.counters.waits),
+		OneTimeCreated: atomic.LoadUint64(&p.counters.oneTimeCreated),
+		OneTimeClosed:  atomic.LoadUint64(&p.counters.oneTimeClosed),
+		Grows:          atomic.LoadUint64(&p.counters.grows),
+		Shrinks:        atomic.LoadUint64(&p.counters.shrinks),
+		DialFailures:   atomic.LoadUint64(&p.counters.dialFailures),
+		BudgetDenials:  atomic.LoadUint64(&p.counters.budgetDenials),
+		Gets:           atomic.LoadUint64(&p.counters.gets),
+		GetLatency:     time.Duration(atomic.LoadInt64(&p.counters.getLatency)),
+
+		GetLatencyHistogram: p.counters.getHistogram(),
+	}
+
+	now := time.Now()
+	p.backoff.Lock()
+	if p.backoff.lastErr != nil {
+		s.LastDialError = p.backoff.lastErr.Error()
+		s.LastDialErrorTime = p.backoff.lastErrTime
+	}
+	if p.backoff.failures > 0 && p.backoff.until.After(now) {
+		s.GrowBackoff = p.backoff.until.Sub(now)
+	}
+	p.backoff.Unlock()
+
+	p.RLock()
+	current := int(atomic.LoadInt32(&p.current))
+	s.Conns = make([]ConnStats, 0, current)
+	for i := 0; i < current; i++ {
+		c := p.conns[i]
+		if c == nil || c.cc == nil {
+			continue
+		}
+		s.Conns = append(s.Conns, ConnStats{
+			Index:    i,
+			State:    c.cc.GetState().String(),
+			Healthy:  c.healthy(),
+			InFlight: int(atomic.LoadInt32(&c.inflight)),
+			Age:      now.Sub(c.created),
+			Uses:     atomic.LoadUint64(&c.uses),
+		})
+	}
+	p.RUnlock()
+
+	s.Current = len(s.Conns)
+	for _, c := range s.Conns {
+		if c.InFlight > 0 {
+			s.Active++
+		}
+	}
+	s.Idle = s.Current - s.Active
+	return s
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Reuse = false
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	s := p.Stats()
+	require.Equal(t, *endpoint, s.Address)
+	require.Equal(t, 1, s.Current)
+	require.Equal(t, 1, s.Idle)
+	require.Equal(t, 0, s.Active)
+	require.Len(t, s.Conns, 1)
+
+	// the second grows the pool, the third is one-time connection.
+	conns := make([]Conn, 3)
+	for i := range conns {
+		conns[i], err = p.Get()
+		require.NoError(t, err)
+		if i == 1 {
+			waitGrow(t, nativePool, 2)
+		}
+	}
+
+	s = p.Stats()
+	require.Equal(t, 2, s.Current)
+	require.Equal(t, s.Current, s.Active+s.Idle)
+	require.Equal(t, 3, s.Ref)
+	require.EqualValues(t, 1, s.Grows)
+	require.EqualValues(t, 1, s.OneTimeCreated)
+	require.EqualValues(t, 0, s.OneTimeClosed)
+	require.EqualValues(t, 3, s.Gets)
+	inflight := 0
+	for i, c := range s.Conns {
+		require.Equal(t, i, c.Index)
+		require.EqualValues(t, c.InFlight, c.Uses)
+		require.True(t, c.Healthy)
+		require.NotEmpty(t, c.State)
+		inflight += c.InFlight
+	}
+	require.Equal(t, 2, inflight)
+
+	for _, c := range conns {
+		require.NoError(t, c.Close())
+	}
+
+	s = p.Stats()
+	require.Equal(t, 1, s.Current)
+	require.Equal(t, 0, s.Ref)
+	require.EqualValues(t, 1, s.Shrinks)
+	require.EqualValues(t, 1, s.OneTimeClosed)
+}
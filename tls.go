@@ -0,0 +1,218 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSReloadInterval is the default interval to check the TLSFiles for changes.
+const TLSReloadInterval = 10 * time.Second
+
+// TLSFiles are the PEM files of TLS and mTLS. The pool checks them for
+// changes, and replaces its connections gracefully one by one after they
+// are reloaded, so that the new handshakes use the rotated certificates.
+type TLSFiles struct {
+	// CertFile and KeyFile are the client certificate of mTLS, both of
+	// them are empty for TLS.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is the root CAs to verify the server, empty means the system roots.
+	CAFile string
+
+	// ServerName verifies the server certificate instead of the address.
+	ServerName string
+
+	// ReloadInterval is the interval to check the files for changes,
+	// zero means the default TLSReloadInterval.
+	ReloadInterval time.Duration
+}
+
+// read return the contents of the files.
+func (f TLSFiles) read() ([][]byte, error) {
+	var contents [][]byte
+	for _, name := range []string{f.CertFile, f.KeyFile, f.CAFile} {
+		if name == "" {
+			contents = append(contents, nil)
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, b)
+	}
+	return contents, nil
+}
+
+// credentials return the TLS credentials of the contents read.
+func (f TLSFiles) credentials(contents [][]byte) (credentials.TransportCredentials, error) {
+	config := &tls.Config{ServerName: f.ServerName, MinVersion: tls.VersionTLS12}
+	if f.CertFile != "" {
+		cert, err := tls.X509KeyPair(contents[0], contents[1])
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if f.CAFile != "" {
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(contents[2]) {
+			return nil, fmt.Errorf("no certificate in CA file %s", f.CAFile)
+		}
+		config.RootCAs = roots
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// load return the TLS credentials of the files.
+func (f TLSFiles) load() (credentials.TransportCredentials, error) {
+	contents, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return f.credentials(contents)
+}
+
+// certWatcher is the transport credentials of the TLSFiles, the handshakes
+// use the credentials last reloaded.
+type certWatcher struct {
+	files TLSFiles
+	creds atomic.Value
+
+	// protect the contents last loaded.
+	mu       sync.Mutex
+	contents [][]byte
+}
+
+func newCertWatcher(files TLSFiles) (*certWatcher, error) {
+	w := &certWatcher{files: files}
+	if _, err := w.reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// reload loads the files if they're changed, and return whether they're
+// reloaded. The credentials are kept if the files are invalid.
+func (w *certWatcher) reload() (bool, error) {
+	contents, err := w.files.read()
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.contents != nil && bytes.Equal(bytes.Join(contents, nil), bytes.Join(w.contents, nil)) {
+		return false, nil
+	}
+	creds, err := w.files.credentials(contents)
+	if err != nil {
+		return false, err
+	}
+	w.creds.Store(creds)
+	w.contents = contents
+	return true, nil
+}
+
+func (w *certWatcher) current() credentials.TransportCredentials {
+	return w.creds.Load().(credentials.TransportCredentials)
+}
+
+// ClientHandshake see credentials.TransportCredentials.
+func (w *certWatcher) ClientHandshake(ctx context.Context, authority string,
+	rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return w.current().ClientHandshake(ctx, authority, rawConn)
+}
+
+// ServerHandshake see credentials.TransportCredentials, it's client only.
+func (w *certWatcher) ServerHandshake(net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tls files are client only")
+}
+
+// Info see credentials.TransportCredentials.
+func (w *certWatcher) Info() credentials.ProtocolInfo {
+	return w.current().Info()
+}
+
+// Clone see credentials.TransportCredentials, the clone shares the reloads.
+func (w *certWatcher) Clone() credentials.TransportCredentials {
+	return w
+}
+
+// OverrideServerName see credentials.TransportCredentials, use TLSFiles.ServerName instead.
+func (w *certWatcher) OverrideServerName(string) error {
+	return errors.New("use TLSFiles.ServerName instead")
+}
+
+// watchCerts reloads the TLSFiles every ReloadInterval until the pool is
+// closed, and rotates the connections after they're reloaded.
+func (p *pool) watchCerts() {
+	interval := p.certs.files.ReloadInterval
+	if interval <= 0 {
+		interval = TLSReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			reloaded, err := p.certs.reload()
+			if err != nil {
+				p.opt.Logger.Warn("reload tls files failed", "address", p.address, "error", err)
+				continue
+			}
+			if reloaded {
+				p.opt.Logger.Info("reload tls files success", "address", p.address)
+				p.rotate()
+			}
+		}
+	}
+}
+
+// rotate replaces all of the connections gracefully one by one, the
+// in-flight logical connections are kept until closed.
+func (p *pool) rotate() {
+	p.RLock()
+	olds := make([]*conn, atomic.LoadInt32(&p.current))
+	copy(olds, p.conns)
+	p.RUnlock()
+
+	for i, c := range olds {
+		if c == nil {
+			continue
+		}
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		p.replace(i, c, EvictCertRotation)
+	}
+}
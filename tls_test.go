@@ -0,0 +1,207 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// testCA issues the certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pool test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue return the PEM certificate and key of the common name.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes the file by rename, the same as the rotation of secrets.
+func writeFile(t *testing.T, name string, data []byte) {
+	require.NoError(t, os.WriteFile(name+".tmp", data, 0600))
+	require.NoError(t, os.Rename(name+".tmp", name))
+}
+
+// newTLSServer starts an mTLS health server, the common names of the
+// client certificates are sent to names.
+func newTLSServer(t *testing.T, ca *testCA, names chan<- string) string {
+	certPEM, keyPEM := ca.issue(t, "echo.test", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(func(ctx context.Context,
+		req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, _ := peer.FromContext(ctx)
+		names <- p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Subject.CommonName
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(listen)
+	t.Cleanup(s.Stop)
+	return listen.Addr().String()
+}
+
+func TestTLSFiles(t *testing.T) {
+	ca := newTestCA(t)
+	names := make(chan string, 1)
+	address := newTLSServer(t, ca, names)
+
+	dir := t.TempDir()
+	files := TLSFiles{
+		CertFile:       filepath.Join(dir, "client.pem"),
+		KeyFile:        filepath.Join(dir, "client.key"),
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ServerName:     "echo.test",
+		ReloadInterval: time.Millisecond,
+	}
+	certPEM, keyPEM := ca.issue(t, "client-1", x509.ExtKeyUsageClientAuth)
+	writeFile(t, files.CertFile, certPEM)
+	writeFile(t, files.KeyFile, keyPEM)
+	writeFile(t, files.CAFile, ca.pem)
+
+	config := DefaultDialConfig
+	config.TLSFiles = &files
+	opt := DefaultOptions
+	opt.DialConfig = &config
+	opt.MaxIdle = 1
+	p, err := New(address, opt)
+	require.NoError(t, err)
+	defer p.Close()
+	nativePool := p.(*pool)
+
+	client := healthpb.NewHealthClient(p)
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "client-1", <-names)
+
+	// the connection is replaced after the client certificate is rotated.
+	nativePool.RLock()
+	old := nativePool.conns[0]
+	nativePool.RUnlock()
+	certPEM, keyPEM = ca.issue(t, "client-2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, files.KeyFile, keyPEM)
+	writeFile(t, files.CertFile, certPEM)
+	waitFor(t, func() bool {
+		nativePool.RLock()
+		defer nativePool.RUnlock()
+		return nativePool.conns[0] != old
+	})
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "client-2", <-names)
+}
+
+func TestTLSFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	files := TLSFiles{CAFile: filepath.Join(dir, "ca.pem")}
+	config := DefaultDialConfig
+	config.TLSFiles = &files
+	opt := DefaultOptions
+	opt.DialConfig = &config
+
+	// the file doesn't exist.
+	_, err := New("127.0.0.1:8080", opt)
+	require.Error(t, err)
+
+	writeFile(t, files.CAFile, []byte("invalid"))
+	_, err = New("127.0.0.1:8080", opt)
+	require.Error(t, err)
+
+	// the invalid files are not reloaded.
+	ca := newTestCA(t)
+	writeFile(t, files.CAFile, ca.pem)
+	w, err := newCertWatcher(files)
+	require.NoError(t, err)
+	creds := w.current()
+	writeFile(t, files.CAFile, []byte("invalid"))
+	reloaded, err := w.reload()
+	require.Error(t, err)
+	require.False(t, reloaded)
+	require.True(t, creds == w.current())
+
+	files.CertFile = filepath.Join(dir, "client.pem")
+	_, err = New("127.0.0.1:8080", opt)
+	var oerr *OptionError
+	require.ErrorAs(t, err, &oerr)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// GetInfo is the detail of a GetContext call, it's filled by the pool if
+// the ctx is returned by WithGetInfo.
+type GetInfo struct {
+	// Index is the slot index of the selected connection, -1 for a one-time
+	// connection.
+	Index int
+
+	// Waited is the time spent waiting for a logical connection or the
+	// first connection of the pool.
+	Waited time.Duration
+
+	// Grew is true if the call started growing the pool.
+	Grew bool
+
+	// OneTime is true if a one-time connection is returned.
+	OneTime bool
+}
+
+type getInfoKey struct{}
+
+// WithGetInfo return a ctx which makes GetContext fill the info.
+func WithGetInfo(ctx context.Context, info *GetInfo) context.Context {
+	return context.WithValue(ctx, getInfoKey{}, info)
+}
+
+// getInfoFrom return the info of the ctx, nil if none.
+func getInfoFrom(ctx context.Context) *GetInfo {
+	info, _ := ctx.Value(getInfoKey{}).(*GetInfo)
+	return info
+}
+
+func (info *GetInfo) waited(d time.Duration) {
+	if info != nil {
+		info.Waited += d
+	}
+}
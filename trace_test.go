@@ -0,0 +1,51 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInfo(t *testing.T) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 2
+	opt.MaxConcurrentStreams = 1
+	opt.Reuse = false
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	defer p.Close()
+
+	var info1, info2, info3 GetInfo
+	conn1, err := p.GetContext(WithGetInfo(context.Background(), &info1))
+	require.NoError(t, err)
+	defer conn1.Close()
+	require.Equal(t, GetInfo{Index: 0}, info1)
+
+	conn2, err := p.GetContext(WithGetInfo(context.Background(), &info2))
+	require.NoError(t, err)
+	defer conn2.Close()
+	require.Equal(t, GetInfo{Index: 0, Grew: true}, info2)
+	waitGrow(t, nativePool, 2)
+
+	conn3, err := p.GetContext(WithGetInfo(context.Background(), &info3))
+	require.NoError(t, err)
+	defer conn3.Close()
+	require.Equal(t, GetInfo{Index: -1, OneTime: true}, info3)
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"fmt"
+	"time"
+)
+
+// OptionError is the error resulting if a field of Options is invalid.
+type OptionError struct {
+	// Field is the name of the invalid field of Options.
+	Field string
+
+	// Value is the invalid value.
+	Value interface{}
+
+	// Reason describes the constraint violated.
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid %s settings: %v, %s", e.Field, e.Value, e.Reason)
+}
+
+// WithDefaults return the options with the zero Dial and limits set from
+// DefaultOptions. MaxIdle is lowered to MaxActive if only MaxActive is set.
+func (o Options) WithDefaults() Options {
+	if o.Dial == nil {
+		o.Dial = DefaultOptions.Dial
+	}
+	if o.MaxActive == 0 {
+		o.MaxActive = DefaultOptions.MaxActive
+		if o.MaxIdle > o.MaxActive {
+			o.MaxActive = o.MaxIdle
+		}
+	}
+	if o.MaxIdle == 0 {
+		o.MaxIdle = DefaultOptions.MaxIdle
+		if o.MaxIdle > o.MaxActive {
+			o.MaxIdle = o.MaxActive
+		}
+	}
+	if o.MaxConcurrentStreams == 0 {
+		o.MaxConcurrentStreams = DefaultOptions.MaxConcurrentStreams
+	}
+	return o
+}
+
+// Validate checks the options, and return an *OptionError of the first
+// invalid field.
+func (o Options) Validate() error {
+	if o.Dial == nil && o.DialConfig == nil {
+		return &OptionError{"Dial", nil, "must be set"}
+	}
+	if err := validateLimits(o.MaxIdle, o.MaxActive, o.MaxConcurrentStreams, o.MinReady); err != nil {
+		return err
+	}
+	if c := o.DialConfig; c != nil && c.TLSFiles != nil && (c.TLSFiles.CertFile == "") != (c.TLSFiles.KeyFile == "") {
+		return &OptionError{"DialConfig.TLSFiles", *c.TLSFiles, "CertFile and KeyFile must be set together"}
+	}
+	if o.InitMode < InitSync || o.InitMode > InitLazy {
+		return &OptionError{"InitMode", o.InitMode, "must be InitSync, InitAsync or InitLazy"}
+	}
+
+	durations := []struct {
+		field string
+		value time.Duration
+	}{
+		{"HealthCheckInterval", o.HealthCheckInterval},
+		{"IdleTimeout", o.IdleTimeout},
+		{"MaxConnLifetime", o.MaxConnLifetime},
+		{"MaxConnLifetimeJitter", o.MaxConnLifetimeJitter},
+		{"LeakThreshold", o.LeakThreshold},
+		{"ShrinkInterval", o.ShrinkInterval},
+		{"RemoveTimeout", o.RemoveTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			return &OptionError{d.field, d.value, "must not be negative"}
+		}
+	}
+
+	// the fields which take no effect without the others.
+	if o.MaxConnLifetimeJitter > 0 && o.MaxConnLifetime == 0 {
+		return &OptionError{"MaxConnLifetimeJitter", o.MaxConnLifetimeJitter, "requires MaxConnLifetime"}
+	}
+	if o.OnLeak != nil && o.LeakThreshold == 0 {
+		return &OptionError{"OnLeak", "func", "requires LeakThreshold"}
+	}
+	if o.ShrinkInterval > 0 && o.ScalingPolicy == nil {
+		return &OptionError{"ShrinkInterval", o.ShrinkInterval, "requires ScalingPolicy"}
+	}
+	return nil
+}
+
+// validateLimits checks the limits changed by Resize.
+func validateLimits(maxIdle, maxActive, maxConcurrentStreams, minReady int) error {
+	if maxIdle <= 0 {
+		return &OptionError{"MaxIdle", maxIdle, "must be positive"}
+	}
+	if maxActive <= 0 {
+		return &OptionError{"MaxActive", maxActive, "must be positive"}
+	}
+	if maxIdle > maxActive {
+		return &OptionError{"MaxIdle", maxIdle, fmt.Sprintf("must not exceed MaxActive %d", maxActive)}
+	}
+	if maxConcurrentStreams <= 0 {
+		return &OptionError{"MaxConcurrentStreams", maxConcurrentStreams, "must be positive"}
+	}
+	if minReady < 0 || minReady > maxIdle {
+		return &OptionError{"MinReady", minReady, fmt.Sprintf("must be in [0, MaxIdle %d]", maxIdle)}
+	}
+	return nil
+}
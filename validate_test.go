@@ -0,0 +1,99 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOptions(t *testing.T) {
+	opt, err := NewOptions(WithDialer(DialTest), WithMaxIdle(2), WithWait(true),
+		WithMaxConnLifetime(time.Minute, time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 2, opt.MaxIdle)
+	require.Equal(t, DefaultOptions.MaxActive, opt.MaxActive)
+	require.True(t, opt.Wait)
+	require.Equal(t, time.Minute, opt.MaxConnLifetime)
+	require.Equal(t, time.Second, opt.MaxConnLifetimeJitter)
+
+	// the default MaxIdle doesn't exceed MaxActive.
+	opt, err = NewOptions(WithMaxActive(4))
+	require.NoError(t, err)
+	require.Equal(t, 4, opt.MaxIdle)
+	require.Equal(t, 4, opt.MaxActive)
+	opt, err = NewOptions()
+	require.NoError(t, err)
+	require.Equal(t, DefaultOptions.MaxIdle, opt.MaxIdle)
+	require.Equal(t, DefaultOptions.MaxActive, opt.MaxActive)
+	require.Equal(t, DefaultOptions.MaxConcurrentStreams, opt.MaxConcurrentStreams)
+	require.Equal(t, DefaultOptions.Reuse, opt.Reuse)
+	require.NotNil(t, opt.Dial)
+
+	_, err = NewOptions(WithMaxIdle(8), WithMaxActive(4))
+	var oerr *OptionError
+	require.True(t, errors.As(err, &oerr))
+	require.Equal(t, "MaxIdle", oerr.Field)
+	require.EqualError(t, err, "invalid MaxIdle settings: 8, must not exceed MaxActive 4")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field  string
+		modify func(*Options)
+	}{
+		{"Dial", func(o *Options) { o.Dial = nil }},
+		{"MaxIdle", func(o *Options) { o.MaxIdle = 0 }},
+		{"MaxActive", func(o *Options) { o.MaxActive = -1 }},
+		{"MaxConcurrentStreams", func(o *Options) { o.MaxConcurrentStreams = 0 }},
+		{"MinReady", func(o *Options) { o.MinReady = o.MaxIdle + 1 }},
+		{"InitMode", func(o *Options) { o.InitMode = InitLazy + 1 }},
+		{"IdleTimeout", func(o *Options) { o.IdleTimeout = -time.Second }},
+		{"MaxConnLifetimeJitter", func(o *Options) { o.MaxConnLifetimeJitter = time.Second }},
+		{"OnLeak", func(o *Options) { o.OnLeak = func(Checkout) {} }},
+		{"ShrinkInterval", func(o *Options) { o.ShrinkInterval = time.Second }},
+	}
+	for _, tt := range tests {
+		opt := DefaultOptions
+		tt.modify(&opt)
+		err := opt.Validate()
+		var oerr *OptionError
+		require.True(t, errors.As(err, &oerr), tt.field)
+		require.Equal(t, tt.field, oerr.Field)
+
+		_, err = New("127.0.0.1:8080", opt)
+		require.Equal(t, oerr, err)
+	}
+	require.NoError(t, DefaultOptions.Validate())
+}
+
+func TestWithDefaults(t *testing.T) {
+	opt := Options{}.WithDefaults()
+	require.NoError(t, opt.Validate())
+	require.Equal(t, DefaultOptions.MaxIdle, opt.MaxIdle)
+	require.Equal(t, DefaultOptions.MaxActive, opt.MaxActive)
+	require.Equal(t, DefaultOptions.MaxConcurrentStreams, opt.MaxConcurrentStreams)
+
+	opt = Options{MaxActive: 4}.WithDefaults()
+	require.Equal(t, 4, opt.MaxIdle)
+	require.Equal(t, 4, opt.MaxActive)
+
+	opt = Options{MaxIdle: 100}.WithDefaults()
+	require.Equal(t, 100, opt.MaxIdle)
+	require.Equal(t, 100, opt.MaxActive)
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// waitQueue is a FIFO queue of callers waiting for a logical connection.
+// Each element is a buffered channel which receives the granted ref,
+// or zero if the pool is closed.
+type waitQueue struct {
+	// atomic, the number of waiting callers, including the ones
+	// which are about to enqueue.
+	count int32
+
+	mu    sync.Mutex
+	queue list.List
+}
+
+func (q *waitQueue) len() int32 {
+	return atomic.LoadInt32(&q.count)
+}
+
+// closeAll wakes up all of the waiters with zero ref.
+func (q *waitQueue) closeAll() {
+	q.mu.Lock()
+	for e := q.queue.Front(); e != nil; e = e.Next() {
+		e.Value.(chan int32) <- 0
+	}
+	q.queue.Init()
+	q.mu.Unlock()
+}
+
+// capacity return the maximum logical connections of pool, the connections
+// can't be afforded by Options.Budget are excluded.
+func (p *pool) capacity() int32 {
+	active := p.maxActive()
+	if p.opt.Budget != nil {
+		if n := atomic.LoadInt32(&p.current) + int32(p.opt.Budget.available()); n < active {
+			active = n
+		}
+	}
+	return active * p.maxStreams()
+}
+
+// tryIncrRef increase the ref only if the pool isn't saturated.
+func (p *pool) tryIncrRef() (int32, bool) {
+	for {
+		ref := atomic.LoadInt32(&p.ref)
+		if ref >= p.capacity() {
+			return ref, false
+		}
+		if atomic.CompareAndSwapInt32(&p.ref, ref, ref+1) {
+			return ref + 1, true
+		}
+	}
+}
+
+// wait increase the ref, blocks until a logical connection is available
+// if the pool is saturated.
+func (p *pool) wait(ctx context.Context) (int32, error) {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		return 0, ErrClosed
+	}
+	q := &p.waiters
+	if q.len() == 0 {
+		if ref, ok := p.tryIncrRef(); ok {
+			return ref, nil
+		}
+	}
+
+	// the count must be increased before the ref is checked again,
+	// otherwise decrRef may miss the waiter.
+	atomic.AddInt32(&q.count, 1)
+	defer atomic.AddInt32(&q.count, -1)
+
+	q.mu.Lock()
+	if atomic.LoadInt32(&p.closed) == 1 {
+		q.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if q.queue.Len() == 0 {
+		if ref, ok := p.tryIncrRef(); ok {
+			q.mu.Unlock()
+			return ref, nil
+		}
+	}
+	ready := make(chan int32, 1)
+	elem := q.queue.PushBack(ready)
+	q.mu.Unlock()
+	atomic.AddUint64(&p.counters.waits, 1)
+
+	select {
+	case ref := <-ready:
+		if ref == 0 {
+			return 0, ErrClosed
+		}
+		return ref, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	select {
+	case ref := <-ready:
+		// granted concurrently, give the logical connection back.
+		q.mu.Unlock()
+		if ref != 0 {
+			p.decrRef()
+		}
+	default:
+		q.queue.Remove(elem)
+		q.mu.Unlock()
+	}
+	return 0, ctx.Err()
+}
+
+// notify grant the free logical connections to waiters in FIFO order.
+func (p *pool) notify() {
+	q := &p.waiters
+	q.mu.Lock()
+	for q.queue.Len() > 0 {
+		ref, ok := p.tryIncrRef()
+		if !ok {
+			break
+		}
+		q.queue.Remove(q.queue.Front()).(chan int32) <- ref
+	}
+	q.mu.Unlock()
+}
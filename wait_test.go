@@ -0,0 +1,125 @@
+// Copyright 2019 shimingyah. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// ee the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newWaitPool(t *testing.T) (Pool, *pool) {
+	opt := DefaultOptions
+	opt.Dial = DialTest
+	opt.MaxIdle = 1
+	opt.MaxActive = 1
+	opt.MaxConcurrentStreams = 1
+	opt.Wait = true
+
+	p, nativePool, _, err := newPool(&opt)
+	require.NoError(t, err)
+	return p, nativePool
+}
+
+// waitFor polls the condition until it's satisfied or one second elapsed.
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not satisfied in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// queued return the number of callers already in the queue.
+func (q *waitQueue) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.queue.Len()
+}
+
+func TestGetContextTimeout(t *testing.T) {
+	p, nativePool := newWaitPool(t)
+	defer p.Close()
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.GetContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.EqualValues(t, 1, nativePool.ref)
+	require.EqualValues(t, 0, nativePool.waiters.len())
+
+	conn1.Close()
+	require.EqualValues(t, 0, nativePool.ref)
+}
+
+func TestGetContextWait(t *testing.T) {
+	p, nativePool := newWaitPool(t)
+	defer p.Close()
+
+	conn1, err := p.Get()
+	require.NoError(t, err)
+
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := p.GetContext(context.Background())
+			require.NoError(t, err)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			conn.Close()
+		}(i)
+		// make sure the waiters are enqueued in order.
+		waitFor(t, func() bool {
+			return nativePool.waiters.queued() == i+1
+		})
+	}
+
+	conn1.Close()
+	wg.Wait()
+	require.Equal(t, []int{0, 1, 2}, order)
+	require.EqualValues(t, 0, nativePool.ref)
+}
+
+func TestGetContextClose(t *testing.T) {
+	p, nativePool := newWaitPool(t)
+
+	_, err := p.Get()
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := p.GetContext(context.Background())
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		return nativePool.waiters.queued() == 1
+	})
+
+	p.Close()
+	require.Equal(t, ErrClosed, <-done)
+}